@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/common-creation/claudeway/internal/agent"
+	"github.com/common-creation/claudeway/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent <name> [flags] [-- args...]",
+	Short: "Start the claudeway container and launch a coding agent",
+	Long: `Start the claudeway container with a built-in agent preset and launch the agent.
+The preset installs the agent during initialization, binds its credentials and
+config from the host, and passes flags that are only safe inside the sandbox,
+such as skipping permission prompts. Arguments after the name are passed to the agent.
+
+Use 'claudeway agent list' to see the available presets, or set 'agent: <name>'
+in claudeway.yaml to make 'claudeway up' launch the agent.`,
+	Args:          cobra.MinimumNArgs(1),
+	RunE:          runAgent,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var agentListCmd = &cobra.Command{
+	Use:           "list",
+	Short:         "List the built-in agent presets",
+	RunE:          runAgentList,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var (
+	agentRecreate  bool
+	agentNoSandbox bool
+)
+
+func init() {
+	// Flags after the agent name belong to the agent
+	agentCmd.Flags().SetInterspersed(false)
+	agentCmd.Flags().BoolVar(&agentRecreate, "recreate", false, "Recreate the container without asking if its configuration is out of date")
+	agentCmd.Flags().BoolVar(&agentNoSandbox, "no-sandbox-flags", false, "Do not pass the preset's sandbox-only flags to the agent")
+	agentCmd.AddCommand(agentListCmd)
+	rootCmd.AddCommand(agentCmd)
+}
+
+func runAgent(cmd *cobra.Command, args []string) error {
+	if err := runAgentInternal(cmd, args); err != nil {
+		exitWithError(err)
+	}
+	return nil
+}
+
+func runAgentInternal(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Load configuration with the requested agent
+	cfg, err := config.LoadWithAgent(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	preset, err := cfg.Agent.Preset()
+	if err != nil {
+		return err
+	}
+
+	// Launch the agent even if a different command is configured
+	sandboxed := cfg.Agent.IsSandboxed() && !agentNoSandbox
+	command := preset.LaunchCommand(sandboxed, append(cfg.Agent.Args, args[1:]...))
+
+	return enterSandbox(ctx, cfg, command, false, agentRecreate)
+}
+
+func runAgentList(cmd *cobra.Command, args []string) error {
+	for _, name := range agent.Names() {
+		preset, _ := agent.Lookup(name)
+		fmt.Printf("%-8s %s\n", name, preset.Description)
+		fmt.Printf("         launch: %s\n", strings.Join(preset.LaunchCommand(true, nil), " "))
+	}
+	if _, err := os.Stat("claudeway.yaml"); err == nil {
+		fmt.Println("\nSet 'agent: <name>' in claudeway.yaml to make 'claudeway up' launch an agent")
+	}
+	return nil
+}
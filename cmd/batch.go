@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/common-creation/claudeway/internal/config"
+	"github.com/common-creation/claudeway/internal/docker"
+	"github.com/common-creation/claudeway/internal/task"
+	"github.com/common-creation/claudeway/internal/term"
+	"github.com/spf13/cobra"
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch <tasks.yaml>",
+	Short: "Run many headless tasks in parallel, each in its own sandbox",
+	Long: `Run every task of a tasks file like 'claudeway task --worktree': each task gets
+its own throwaway container and git worktree of the project at HEAD, so tasks
+cannot interfere with each other or with the project directory.
+
+The tasks file lists prompts or commands:
+
+  concurrency: 4
+  timeout: 30m
+  agent: claude
+  tasks:
+    - name: refactor-api
+      prompt: Replace the deprecated client in internal/api.
+    - name: refactor-db
+      prompt_file: prompts/db.md
+    - name: fmt
+      command: make fmt
+
+The transcript, sandbox log, patch and report of each task are written to a
+directory per task in the output directory, together with summary.json.
+claudeway exits with 1 unless every task succeeded.`,
+	Args:          cobra.ExactArgs(1),
+	RunE:          runBatch,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var (
+	batchConcurrency int
+	batchTimeout     time.Duration
+	batchOutput      string
+)
+
+func init() {
+	batchCmd.Flags().IntVarP(&batchConcurrency, "concurrency", "j", 0, "Maximum number of tasks running at the same time (default: the tasks file's value or 1)")
+	batchCmd.Flags().DurationVar(&batchTimeout, "timeout", 30*time.Minute, "Default timeout of each task, overrides the tasks file's value if set")
+	batchCmd.Flags().StringVarP(&batchOutput, "output", "o", "", "Output directory (default: a new directory in the XDG state directory)")
+	rootCmd.AddCommand(batchCmd)
+}
+
+func runBatch(cmd *cobra.Command, args []string) error {
+	if err := runBatchInternal(cmd, args); err != nil {
+		exitWithError(err)
+	}
+	return nil
+}
+
+func runBatchInternal(cmd *cobra.Command, args []string) error {
+	batch, err := task.LoadBatch(args[0])
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("timeout") || batch.Timeout == 0 {
+		batch.Timeout = batchTimeout
+	}
+
+	workDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	// Build the image once instead of in every task
+	fmt.Println("Checking Docker image...")
+	if err := docker.BuildDockerImage(); err != nil {
+		return fmt.Errorf("failed to build Docker image: %w", err)
+	}
+
+	outputDir := batchOutput
+	if outputDir == "" {
+		// The random suffix keeps batches started in the same second apart
+		batches := filepath.Join(config.GetStateDir(), "claudeway", "batches")
+		if err := os.MkdirAll(batches, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		dir, err := os.MkdirTemp(batches, time.Now().Format("20060102-150405")+"-")
+		if err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		outputDir = dir
+	} else if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Cancel running tasks on interrupt so their sandboxes are still cleaned up
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	names := make([]string, len(batch.Tasks))
+	for i, t := range batch.Tasks {
+		names[i] = t.Name
+	}
+	progress := newBatchProgress(os.Stdout, names)
+	defer progress.Close()
+
+	result := task.RunBatch(ctx, workDir, batch, task.BatchOptions{
+		Concurrency: batchConcurrency,
+		OutputDir:   outputDir,
+		Progress:    progress.Update,
+		Warn:        progress.Warn,
+	})
+	progress.Close()
+
+	fmt.Println()
+	for _, report := range result.Tasks {
+		fmt.Printf("  %s: %s\n", report.Name, report.Summary())
+	}
+	fmt.Printf("\n%d succeeded, %d failed in %s\n", result.Succeeded, result.Failed, time.Duration(result.DurationSeconds*float64(time.Second)).Round(time.Second))
+	fmt.Printf("Output saved to %s\n", outputDir)
+
+	if result.Failed > 0 {
+		return &docker.ExitError{Code: 1}
+	}
+	return nil
+}
+
+// batchProgress shows the phase of every task. On a terminal the list is
+// redrawn in place, otherwise a line is printed for every change.
+type batchProgress struct {
+	mu      sync.Mutex
+	out     io.Writer
+	tty     bool
+	names   []string
+	phases  []string
+	started []time.Time
+	ended   []time.Time
+	drawn   int
+	done    chan struct{}
+	closed  sync.Once
+}
+
+func newBatchProgress(out *os.File, names []string) *batchProgress {
+	p := &batchProgress{
+		out:     out,
+		tty:     term.IsTerminal(out.Fd()),
+		names:   names,
+		phases:  make([]string, len(names)),
+		started: make([]time.Time, len(names)),
+		ended:   make([]time.Time, len(names)),
+		done:    make(chan struct{}),
+	}
+	for i := range p.phases {
+		p.phases[i] = "pending"
+	}
+
+	if p.tty {
+		p.draw()
+		// Keep the elapsed times current
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					p.mu.Lock()
+					p.draw()
+					p.mu.Unlock()
+				case <-p.done:
+					return
+				}
+			}
+		}()
+	}
+	return p
+}
+
+// Update records the new phase of a task
+func (p *batchProgress) Update(index int, phase string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.started[index].IsZero() {
+		p.started[index] = time.Now()
+	}
+	switch phase {
+	case task.PhaseStarting, task.PhaseRunning, task.PhaseCollecting:
+	default:
+		p.ended[index] = time.Now()
+	}
+	p.phases[index] = phase
+
+	if p.tty {
+		p.draw()
+	} else {
+		fmt.Fprintf(p.out, "[%s] %s\n", p.names[index], phase)
+	}
+}
+
+// Warn prints a warning of a task. On a terminal it is printed above the
+// list, which is then redrawn below it.
+func (p *batchProgress) Warn(index int, message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.tty {
+		fmt.Fprintf(p.out, "[%s] %s\n", p.names[index], message)
+		return
+	}
+	if p.drawn > 0 {
+		fmt.Fprintf(p.out, "\033[%dA", p.drawn)
+		p.drawn = 0
+	}
+	fmt.Fprintf(p.out, "\033[K[%s] %s\n", p.names[index], message)
+	p.draw()
+}
+
+// Close stops redrawing, it is safe to call more than once
+func (p *batchProgress) Close() {
+	p.closed.Do(func() {
+		close(p.done)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.tty {
+			p.draw()
+		}
+	})
+}
+
+// draw must be called with mu held
+func (p *batchProgress) draw() {
+	width := 0
+	for _, name := range p.names {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+
+	// Move back to the first line of the previous drawing
+	if p.drawn > 0 {
+		fmt.Fprintf(p.out, "\033[%dA", p.drawn)
+	}
+	for i, name := range p.names {
+		elapsed := ""
+		if !p.started[i].IsZero() {
+			end := p.ended[i]
+			if end.IsZero() {
+				end = time.Now()
+			}
+			elapsed = end.Sub(p.started[i]).Round(time.Second).String()
+		}
+		fmt.Fprintf(p.out, "\033[K  %-*s  %-10s  %s\n", width, name, p.phases[i], elapsed)
+	}
+	p.drawn = len(p.names)
+}
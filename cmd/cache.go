@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/common-creation/claudeway/internal/config"
+	"github.com/common-creation/claudeway/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage shared cache volumes",
+	Long: `Manage the cache volumes configured in the caches section of claudeway.yaml.
+Cache volumes are shared by all projects and survive container removal.`,
+}
+
+var cacheLsCmd = &cobra.Command{
+	Use:           "ls",
+	Short:         "List cache volumes",
+	RunE:          runCacheLs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var cacheDuCmd = &cobra.Command{
+	Use:           "du",
+	Short:         "Show disk usage of cache volumes",
+	RunE:          runCacheDu,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var cacheRmCmd = &cobra.Command{
+	Use:           "rm [name...]",
+	Short:         "Remove cache volumes",
+	RunE:          runCacheRm,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var cacheRmAll bool
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheLsCmd)
+	cacheCmd.AddCommand(cacheDuCmd)
+	cacheCmd.AddCommand(cacheRmCmd)
+	cacheRmCmd.Flags().BoolVar(&cacheRmAll, "all", false, "Remove all cache volumes")
+}
+
+func runCacheLs(cmd *cobra.Command, args []string) error {
+	if err := runCacheLsInternal(cmd, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runCacheLsInternal(cmd *cobra.Command, args []string) error {
+	caches, err := docker.ListCaches(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if len(caches) == 0 {
+		fmt.Println("No cache volumes found")
+		fmt.Printf("Available presets: %v\n", config.CachePresetNames())
+		return nil
+	}
+
+	fmt.Printf("%-12s %-28s %s\n", "NAME", "VOLUME", "PATH")
+	for _, cache := range caches {
+		fmt.Printf("%-12s %-28s %s\n", cache.Name, cache.Volume, cache.Path)
+	}
+	return nil
+}
+
+func runCacheDu(cmd *cobra.Command, args []string) error {
+	if err := runCacheDuInternal(cmd, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runCacheDuInternal(cmd *cobra.Command, args []string) error {
+	caches, err := docker.CacheDiskUsage(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if len(caches) == 0 {
+		fmt.Println("No cache volumes found")
+		return nil
+	}
+
+	var total int64
+	fmt.Printf("%-12s %10s %8s\n", "NAME", "SIZE", "IN USE")
+	for _, cache := range caches {
+		size := "unknown"
+		if cache.Size >= 0 {
+			size = formatBytes(uint64(cache.Size))
+			total += cache.Size
+		}
+		inUse := "no"
+		if cache.RefCount > 0 {
+			inUse = "yes"
+		}
+		fmt.Printf("%-12s %10s %8s\n", cache.Name, size, inUse)
+	}
+	fmt.Printf("%-12s %10s\n", "TOTAL", formatBytes(uint64(total)))
+	return nil
+}
+
+func runCacheRm(cmd *cobra.Command, args []string) error {
+	if err := runCacheRmInternal(cmd, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runCacheRmInternal(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	names := args
+	if cacheRmAll {
+		caches, err := docker.ListCaches(ctx)
+		if err != nil {
+			return err
+		}
+		names = nil
+		for _, cache := range caches {
+			names = append(names, cache.Name)
+		}
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("specify the caches to remove or use --all")
+	}
+
+	for _, name := range names {
+		if err := docker.RemoveCache(ctx, name); err != nil {
+			return err
+		}
+		fmt.Printf("Removed cache %s\n", name)
+	}
+	return nil
+}
@@ -2,8 +2,11 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/common-creation/claudeway/internal/docker"
@@ -13,32 +16,75 @@ var execCmd = &cobra.Command{
 	Use:   "exec [command...]",
 	Short: "Execute a command in the running claudeway container",
 	Long: `Execute a command in the running claudeway container for the current directory.
-If no command is specified, it will open an interactive bash shell.`,
+If no command is specified, it will open an interactive bash shell.
+When stdin or stdout is not a terminal, the command runs without a TTY so it can be
+used in scripts, and claudeway exits with the command's exit code.
+
+Commands run as the host user in the project directory unless --root, --user
+or --workdir is given.`,
 	RunE:          runExec,
 	SilenceUsage:  true,
 	SilenceErrors: true,
 }
 
+var (
+	execRecreate bool
+	execRoot     bool
+	execUser     string
+	execWorkdir  string
+	execEnv      []string
+)
+
 func init() {
+	// Flags after the command belong to the command
+	execCmd.Flags().SetInterspersed(false)
+	execCmd.Flags().BoolVar(&execRecreate, "recreate", false, "Recreate the container without asking if its configuration is out of date")
+	execCmd.Flags().BoolVar(&execRoot, "root", false, "Run the command as root")
+	execCmd.Flags().StringVarP(&execUser, "user", "u", "", "Run the command as this user (name or uid[:gid])")
+	execCmd.Flags().StringVarP(&execWorkdir, "workdir", "w", "", "Working directory, relative paths are relative to the current directory")
+	execCmd.Flags().StringArrayVarP(&execEnv, "env", "e", nil, "Set an environment variable (KEY=VALUE, or KEY to pass the host's value)")
 	rootCmd.AddCommand(execCmd)
 }
 
 func runExec(cmd *cobra.Command, args []string) error {
 	if err := runExecInternal(cmd, args); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		exitWithError(err)
 	}
 	return nil
 }
 
+// exitWithError prints err and exits with status 1. If err carries the exit
+// code of a command run in the container, it exits with that code instead.
+func exitWithError(err error) {
+	var exitErr *docker.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.Code)
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
+}
+
 func runExecInternal(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	options, err := execOptions(args)
+	if err != nil {
+		return err
+	}
+
+	// Load configuration
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
 	// Create Docker manager
 	manager, err := docker.NewManager()
 	if err != nil {
 		return fmt.Errorf("failed to create docker manager: %w", err)
 	}
+	// Progress goes to stderr, stdout is the command's
+	manager.SetOutput(os.Stderr)
 
 	// Check if container is running
 	running, err := manager.IsContainerRunning(ctx)
@@ -50,10 +96,56 @@ func runExecInternal(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no running container found for the current directory. Use 'claudeway up' to start one")
 	}
 
-	// Exec into the container
-	if err := manager.ExecInteractive(ctx, args); err != nil {
-		return fmt.Errorf("failed to exec into container: %w", err)
+	// Recreate the container if the configuration changed since it was created
+	removed, err := reconcileDrift(ctx, manager, cfg, execRecreate)
+	if err != nil {
+		return err
+	}
+	if removed {
+		fmt.Fprintln(os.Stderr, "Checking Docker image...")
+		if _, err := docker.BuildImageWithOptions(ctx, docker.BuildOptions{Output: os.Stderr}); err != nil {
+			return fmt.Errorf("failed to build Docker image: %w", err)
+		}
+		if err := startContainer(ctx, manager, cfg); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	// Exec into the container, the command's exit code is passed through
+	err = manager.ExecInteractive(ctx, options)
+	warnForeignFilesAfterSession(ctx, manager)
+	return err
+}
+
+// execOptions returns the exec options for the command and the flags
+func execOptions(args []string) (docker.ExecOptions, error) {
+	options := docker.ExecOptions{Cmd: args}
+
+	switch {
+	case execRoot && execUser != "":
+		return options, fmt.Errorf("--root and --user cannot be used together")
+	case execRoot:
+		options.User = "root"
+		options.Env = []string{"HOME=/root", "USER=root", "LOGNAME=root"}
+	case execUser != "":
+		options.User = execUser
+	}
+
+	if execWorkdir != "" {
+		workdir, err := filepath.Abs(execWorkdir)
+		if err != nil {
+			return options, fmt.Errorf("failed to get absolute path for %s: %w", execWorkdir, err)
+		}
+		options.WorkingDir = workdir
+	}
+
+	for _, env := range execEnv {
+		if strings.Contains(env, "=") {
+			options.Env = append(options.Env, env)
+		} else if value, ok := os.LookupEnv(env); ok {
+			// Like docker, a name alone passes the host's value if it is set
+			options.Env = append(options.Env, env+"="+value)
+		}
+	}
+	return options, nil
 }
\ No newline at end of file
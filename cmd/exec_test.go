@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+// captureStdout returns what fn writes to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	file, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	previous := os.Stdout
+	os.Stdout = file
+	defer func() { os.Stdout = previous }()
+	fn()
+
+	output, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(output)
+}
+
+func TestExecStdoutIsTheCommands(t *testing.T) {
+	runtime, cfg := setupProject(t, "init:\n  - echo ready\n")
+	runtime.ExecHandler = func(container string, config types.ExecConfig, stdin io.Reader, stdout, stderr io.Writer) int {
+		fmt.Fprintln(stdout, "hello")
+		return 0
+	}
+	if err := enterSandbox(context.Background(), cfg, nil, true, false); err != nil {
+		t.Fatalf("up -d failed: %v", err)
+	}
+
+	// An out of date container is reported, but not on stdout
+	if err := os.WriteFile("claudeway.yaml", []byte("init:\n  - echo changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	output := captureStdout(t, func() {
+		err = runExecInternal(execCmd, []string{"echo", "hello"})
+	})
+	if err != nil {
+		t.Fatalf("exec failed: %v", err)
+	}
+	if output != "hello\n" {
+		t.Fatalf("stdout = %q, want only the command's output", output)
+	}
+}
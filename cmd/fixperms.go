@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/common-creation/claudeway/internal/docker"
+	"github.com/common-creation/claudeway/internal/term"
+	"github.com/common-creation/claudeway/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var fixPermsCmd = &cobra.Command{
+	Use:   "fix-perms",
+	Short: "Take back workspace files owned by other users",
+	Long: `Find files in the current project that are not owned by you, such as a
+node_modules directory created by an init step or a command running as root,
+and change their owner back to you. The owner is changed by root in the running
+container, so no sudo is needed on the host.`,
+	RunE:          runFixPerms,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var fixPermsDryRun bool
+
+func init() {
+	fixPermsCmd.Flags().BoolVar(&fixPermsDryRun, "dry-run", false, "Only list the files that would be changed")
+	rootCmd.AddCommand(fixPermsCmd)
+}
+
+func runFixPerms(cmd *cobra.Command, args []string) error {
+	if err := runFixPermsInternal(cmd, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runFixPermsInternal(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if !docker.MapsHostUser() {
+		fmt.Println("Commands run as root in the container on this host, there is nothing to fix")
+		return nil
+	}
+
+	manager, err := docker.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create docker manager: %w", err)
+	}
+
+	// The files of a synced workspace are this host's copies, owned by you
+	synced, err := manager.SyncsWorkspace(ctx)
+	if err != nil {
+		return err
+	}
+	if synced {
+		fmt.Println("The workspace is synced into the container, its files here are already owned by you")
+		return nil
+	}
+
+	workDir := manager.GetWorkDir()
+	files, err := workspace.FindForeignFiles(workDir, os.Getuid())
+	if err != nil {
+		return fmt.Errorf("failed to scan the workspace: %w", err)
+	}
+	if len(files) == 0 {
+		fmt.Println("All files in the workspace are owned by you")
+		return nil
+	}
+
+	fmt.Println("Not owned by you:")
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		fmt.Printf("  %s (uid %d)\n", displayPath(workDir, file), file.UID)
+		paths = append(paths, file.Path)
+	}
+	if fixPermsDryRun {
+		return nil
+	}
+
+	running, err := manager.IsContainerRunning(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("no running container found for the current directory. Use 'claudeway up -d' to start one")
+	}
+
+	if err := manager.FixOwnership(ctx, paths); err != nil {
+		return fmt.Errorf("failed to fix ownership: %w", err)
+	}
+	fmt.Printf("Changed the owner of %d paths back to you\n", len(paths))
+	return nil
+}
+
+// warnForeignFiles points out workspace files that are no longer owned by
+// the host user, which commands running as root in the container leave
+// behind. It runs after initialization and after interactive sessions.
+// Synced workspaces and bind mounts outside Linux are not checked, their
+// files keep the host user as owner.
+func warnForeignFiles(ctx context.Context, manager *docker.Manager) {
+	if !docker.MapsHostUser() || runtime.GOOS != "linux" {
+		return
+	}
+	if synced, err := manager.SyncsWorkspace(ctx); err != nil || synced {
+		return
+	}
+
+	workDir := manager.GetWorkDir()
+	files, err := workspace.FindForeignFiles(workDir, os.Getuid())
+	if err != nil || len(files) == 0 {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: %d paths in the workspace are not owned by you, such as %s\n", len(files), displayPath(workDir, files[0]))
+	fmt.Fprintln(os.Stderr, "Run 'claudeway fix-perms' to take them back")
+}
+
+// warnForeignFilesAfterSession runs warnForeignFiles after a session on a
+// terminal. Scripted calls are not slowed down by a scan of the workspace.
+func warnForeignFilesAfterSession(ctx context.Context, manager *docker.Manager) {
+	if term.IsTerminal(os.Stdin.Fd()) && term.IsTerminal(os.Stdout.Fd()) {
+		warnForeignFiles(ctx, manager)
+	}
+}
+
+// displayPath returns the path of file relative to the workspace, with a
+// trailing slash for directories
+func displayPath(workDir string, file workspace.ForeignFile) string {
+	path := file.Path
+	if rel, err := filepath.Rel(workDir, file.Path); err == nil {
+		path = rel
+	}
+	if file.Dir {
+		path += string(filepath.Separator)
+	}
+	return path
+}
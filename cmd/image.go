@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/common-creation/claudeway/internal/docker"
 	"github.com/spf13/cobra"
@@ -24,12 +26,50 @@ var buildCmd = &cobra.Command{
 	SilenceErrors: true,
 }
 
-var noCache bool
+var imageLsCmd = &cobra.Command{
+	Use:           "ls",
+	Short:         "List claudeway images",
+	Long:          `List the images built by claudeway: builds of the claudeway image, tagged by a hash of their Docker assets and base images, and init images of projects`,
+	RunE:          runImageLs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var imageRmCmd = &cobra.Command{
+	Use:           "rm <image>...",
+	Short:         "Remove claudeway images",
+	Long:          `Remove claudeway images by tag, ID, or the hash part of their tag`,
+	Args:          cobra.MinimumNArgs(1),
+	RunE:          runImageRm,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var imageInspectCmd = &cobra.Command{
+	Use:           "inspect [image]",
+	Short:         "Show details of a claudeway image",
+	Long:          `Show the tags, base images and build hash of a claudeway image, the current one by default`,
+	Args:          cobra.MaximumNArgs(1),
+	RunE:          runImageInspect,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var (
+	noCache      bool
+	pullBase     bool
+	imageRmForce bool
+)
 
 func init() {
 	rootCmd.AddCommand(imageCmd)
 	imageCmd.AddCommand(buildCmd)
+	imageCmd.AddCommand(imageLsCmd)
+	imageCmd.AddCommand(imageRmCmd)
+	imageCmd.AddCommand(imageInspectCmd)
 	buildCmd.Flags().BoolVar(&noCache, "no-cache", false, "Do not use cache when building the image")
+	buildCmd.Flags().BoolVar(&pullBase, "pull", false, "Pull newer versions of the base images")
+	imageRmCmd.Flags().BoolVarP(&imageRmForce, "force", "f", false, "Remove images used by containers")
 }
 
 func runBuild(cmd *cobra.Command, args []string) error {
@@ -41,17 +81,127 @@ func runBuild(cmd *cobra.Command, args []string) error {
 }
 
 func runBuildInternal(cmd *cobra.Command, args []string) error {
-	fmt.Println("Building Docker image...")
-	
 	ctx := context.Background()
-	err := docker.BuildImageWithOptions(ctx, docker.BuildOptions{
+	result, err := docker.BuildImageWithOptions(ctx, docker.BuildOptions{
 		NoCache: noCache,
+		Pull:    pullBase,
 	})
-	
 	if err != nil {
 		return fmt.Errorf("failed to build image: %w", err)
 	}
-	
-	fmt.Println("Image built successfully")
+
+	if !result.Built {
+		fmt.Printf("Image %s is up to date\n", result.Tag)
+	}
+	return nil
+}
+
+func runImageLs(cmd *cobra.Command, args []string) error {
+	if err := runImageLsInternal(cmd, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runImageLsInternal(cmd *cobra.Command, args []string) error {
+	images, err := docker.ListImages(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if len(images) == 0 {
+		fmt.Println("No claudeway images found")
+		return nil
+	}
+
+	fmt.Printf("%-40s %-12s %-16s %10s %s\n", "IMAGE", "ID", "CREATED", "SIZE", "CONTAINERS")
+	for _, image := range images {
+		name := "<none>"
+		if len(image.Tags) > 0 {
+			name = strings.Join(image.Tags, ",")
+		}
+		fmt.Printf("%-40s %-12s %-16s %10s %d\n", name, docker.ShortID(image.ID), image.Created.Format("2006-01-02 15:04"), formatBytes(uint64(image.Size)), image.Containers)
+	}
+	return nil
+}
+
+func runImageRm(cmd *cobra.Command, args []string) error {
+	if err := runImageRmInternal(cmd, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runImageRmInternal(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	for _, ref := range args {
+		image, err := docker.RemoveImage(ctx, ref, imageRmForce)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed image %s\n", docker.ShortID(image.ID))
+		if image.Current() {
+			fmt.Println("It was the current image, it is rebuilt on the next start")
+		}
+	}
+	return nil
+}
+
+func runImageInspect(cmd *cobra.Command, args []string) error {
+	if err := runImageInspectInternal(cmd, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+func runImageInspectInternal(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	ref := docker.ImageName
+	if len(args) > 0 {
+		ref = args[0]
+	}
+
+	image, err := docker.InspectImage(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("ID:         %s\n", image.ID)
+	fmt.Printf("Tags:       %s\n", strings.Join(image.Tags, ", "))
+	fmt.Printf("Created:    %s\n", image.Created.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Size:       %s\n", formatBytes(uint64(image.Size)))
+	fmt.Printf("Containers: %d\n", image.Containers)
+	if image.Project != "" {
+		fmt.Printf("Project:    %s\n", image.Project)
+	}
+	if image.Hash != "" {
+		fmt.Printf("Hash:       %s\n", image.Hash)
+	}
+	if len(image.Bases) > 0 {
+		fmt.Println("Base images:")
+		refs := make([]string, 0, len(image.Bases))
+		for ref := range image.Bases {
+			refs = append(refs, ref)
+		}
+		sort.Strings(refs)
+		for _, ref := range refs {
+			fmt.Printf("  %s  %s\n", ref, image.Bases[ref])
+		}
+	}
+
+	if image.Current() && image.Project == "" {
+		hash, err := docker.CurrentImageHash(ctx)
+		if err != nil {
+			return err
+		}
+		if hash == image.Hash {
+			fmt.Println("Status:     up to date")
+		} else {
+			fmt.Println("Status:     outdated, rebuilt on the next start")
+		}
+	}
+	return nil
+}
@@ -1,11 +1,9 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/common-creation/claudeway/internal/assets"
@@ -76,15 +74,6 @@ func initGlobalConfig() error {
 		fmt.Printf("Created %s\n", dockerfilePath)
 	}
 
-	entrypointPath := filepath.Join(libDir, "entrypoint.sh")
-	if shouldWriteFile(entrypointPath, "entrypoint.sh") {
-		if err := os.WriteFile(entrypointPath, []byte(assets.EntrypointContent), 0755); err != nil {
-			return fmt.Errorf("failed to write entrypoint.sh: %w", err)
-		}
-		fmt.Printf("Created %s\n", entrypointPath)
-	}
-
-
 	// Create global config if it doesn't exist
 	globalConfigPath := filepath.Join(claudewayDir, "claudeway.yaml")
 	if _, err := os.Stat(globalConfigPath); os.IsNotExist(err) {
@@ -103,11 +92,7 @@ func initGlobalConfig() error {
 func shouldWriteFile(path, name string) bool {
 	if _, err := os.Stat(path); err == nil {
 		// File exists, ask for confirmation
-		reader := bufio.NewReader(os.Stdin)
-		fmt.Printf("%s already exists. Overwrite? (y/N): ", name)
-		response, _ := reader.ReadString('\n')
-		response = strings.TrimSpace(strings.ToLower(response))
-		return response == "y" || response == "yes"
+		return confirm(fmt.Sprintf("%s already exists. Overwrite?", name))
 	}
 	return true
 }
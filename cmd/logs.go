@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/common-creation/claudeway/internal/docker"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the logs of the claudeway container",
+	Long: `Show the logs of the claudeway container for the current directory.
+With --init, show the persisted output of the most recent initialization run instead,
+which is kept even after the container has been removed.`,
+	RunE:          runLogs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var (
+	logsFollow  bool
+	logsSince   string
+	logsInit    bool
+	logsHistory bool
+)
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Follow log output")
+	logsCmd.Flags().StringVar(&logsSince, "since", "", "Show logs since a timestamp (e.g. 2024-01-02T13:23:37Z) or relative duration (e.g. 42m)")
+	logsCmd.Flags().BoolVar(&logsInit, "init", false, "Show the output of the most recent initialization run")
+	logsCmd.Flags().BoolVar(&logsHistory, "history", false, "List the persisted initialization runs")
+	rootCmd.AddCommand(logsCmd)
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	if err := runLogsInternal(cmd, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runLogsInternal(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Create Docker manager
+	manager, err := docker.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create docker manager: %w", err)
+	}
+
+	if logsInit || logsHistory {
+		if logsFollow || logsSince != "" {
+			return fmt.Errorf("--follow and --since cannot be used with --init or --history")
+		}
+		return showInitLogs(manager)
+	}
+
+	exists, err := manager.ContainerExists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("no container found for the current directory. Use 'claudeway logs --init' to show persisted init logs")
+	}
+
+	return manager.StreamLogs(ctx, docker.LogsOptions{
+		Follow: logsFollow,
+		Since:  logsSince,
+	}, os.Stdout)
+}
+
+func showInitLogs(manager *docker.Manager) error {
+	logs, err := manager.InitLogs()
+	if err != nil {
+		return fmt.Errorf("failed to list init logs: %w", err)
+	}
+	if len(logs) == 0 {
+		fmt.Println("No initialization logs found for the current directory")
+		return nil
+	}
+
+	if logsHistory {
+		fmt.Printf("Initialization logs in %s:\n", manager.InitLogDir())
+		for _, log := range logs {
+			fmt.Printf("  %s\n", filepath.Base(log))
+		}
+		return nil
+	}
+
+	file, err := os.Open(logs[len(logs)-1])
+	if err != nil {
+		return fmt.Errorf("failed to open init log: %w", err)
+	}
+	defer file.Close()
+
+	_, err = io.Copy(os.Stdout, file)
+	return err
+}
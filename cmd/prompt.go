@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/common-creation/claudeway/internal/term"
+)
+
+// confirm asks a yes/no question on stdin and defaults to no. stdin is not
+// read unless it is a terminal, it may hold input meant for a command. The
+// question goes to stderr, stdout may be piped.
+func confirm(question string) bool {
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		fmt.Fprintf(os.Stderr, "%s (y/N): N (stdin is not a terminal)\n", question)
+		return false
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Fprintf(os.Stderr, "%s (y/N): ", question)
+	response, _ := reader.ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes"
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/common-creation/claudeway/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove orphaned sandboxes, dangling images and volumes",
+	Long: `Remove stopped claudeway containers whose project directory no longer exists
+or that have been idle longer than --idle, plus dangling claudeway images and volumes
+and earlier builds of the claudeway image.
+Running containers are never removed. Shared cache volumes are kept,
+use 'claudeway cache rm' to remove them.`,
+	RunE:          runPrune,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var (
+	pruneForce  bool
+	pruneDryRun bool
+	pruneIdle   time.Duration
+)
+
+func init() {
+	pruneCmd.Flags().BoolVarP(&pruneForce, "force", "f", false, "Do not prompt for confirmation")
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "Only list what would be removed")
+	pruneCmd.Flags().DurationVar(&pruneIdle, "idle", 30*24*time.Hour, "Remove stopped sandboxes unused for longer than this (0 to disable)")
+	rootCmd.AddCommand(pruneCmd)
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	if err := runPruneInternal(cmd, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runPruneInternal(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	candidates, err := docker.FindPruneCandidates(ctx, docker.PruneOptions{
+		IdleAfter: pruneIdle,
+	})
+	if err != nil {
+		return err
+	}
+
+	if candidates.Empty() {
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+
+	if len(candidates.Containers) > 0 {
+		fmt.Println("Containers:")
+		for _, c := range candidates.Containers {
+			project := c.Project
+			if project == "" {
+				project = "(unknown project)"
+			}
+			fmt.Printf("  %s  %s  (%s)\n", c.Name, project, c.Reason)
+		}
+	}
+	if len(candidates.Images) > 0 {
+		fmt.Println("Images:")
+		for _, image := range candidates.Images {
+			fmt.Printf("  %s  created %s  %s\n", docker.ShortID(image.ID), image.Created.Format("2006-01-02 15:04"), formatBytes(uint64(image.Size)))
+		}
+	}
+	if len(candidates.Volumes) > 0 {
+		fmt.Println("Volumes:")
+		for _, volume := range candidates.Volumes {
+			fmt.Printf("  %s\n", volume.Name)
+		}
+	}
+	if len(candidates.Workspaces) > 0 {
+		fmt.Println("Workspace volumes of removed projects:")
+		for _, volume := range candidates.Workspaces {
+			fmt.Printf("  %s  %s\n", volume.Name, volume.Project)
+		}
+		fmt.Fprintln(os.Stderr, "Warning: changes in these workspace volumes that were not synced back are lost")
+	}
+
+	if pruneDryRun {
+		return nil
+	}
+
+	question := fmt.Sprintf("Remove %d container(s), %d image(s) and %d volume(s)?",
+		len(candidates.Containers), len(candidates.Images), len(candidates.Volumes)+len(candidates.Workspaces))
+	if !pruneForce && !confirm(question) {
+		fmt.Println("Aborted")
+		return nil
+	}
+
+	return docker.Prune(ctx, candidates)
+}
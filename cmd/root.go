@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/common-creation/claudeway/internal/docker"
 	"github.com/spf13/cobra"
 )
 
@@ -12,8 +13,15 @@ var rootCmd = &cobra.Command{
 	Short: "A CLI tool for running AI agents safely in Docker containers",
 	Long: `claudeway is a CLI tool that allows you to run AI agents like Claude Code
 safely in Docker containers with proper directory bindings and environment isolation.`,
+	PersistentPreRunE: selectEndpoint,
 }
 
+// Global daemon selection
+var (
+	globalContext string
+	globalHost    string
+)
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -22,4 +30,14 @@ func Execute() {
 }
 
 func init() {
-}
\ No newline at end of file
+	rootCmd.PersistentFlags().StringVar(&globalContext, "context", "", "Docker context to use (overrides DOCKER_HOST, DOCKER_CONTEXT and 'docker context use')")
+	rootCmd.PersistentFlags().StringVarP(&globalHost, "host", "H", "", "Daemon address to connect to, such as ssh://user@host")
+}
+
+func selectEndpoint(cmd *cobra.Command, args []string) error {
+	if globalContext != "" && globalHost != "" {
+		return fmt.Errorf("conflicting options: either specify --host or --context, not both")
+	}
+	docker.SetEndpoint(globalContext, globalHost)
+	return nil
+}
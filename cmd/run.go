@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/common-creation/claudeway/internal/config"
+	"github.com/common-creation/claudeway/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run [flags] -- command...",
+	Short: "Run a command in a throwaway claudeway container",
+	Long: `Create a new container from the project configuration, wait for its initialization,
+run the command and remove the container again, like 'docker run --rm'.
+claudeway exits with the command's exit code.`,
+	Args:          cobra.MinimumNArgs(1),
+	RunE:          runRun,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var runKeep bool
+
+func init() {
+	// Flags after the command belong to the command
+	runCmd.Flags().SetInterspersed(false)
+	runCmd.Flags().BoolVar(&runKeep, "keep", false, "Keep the container after the command exits for debugging")
+	rootCmd.AddCommand(runCmd)
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	if err := runRunInternal(cmd, args); err != nil {
+		exitWithError(err)
+	}
+	return nil
+}
+
+func runRunInternal(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Create Docker manager
+	manager, err := docker.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create docker manager: %w", err)
+	}
+
+	ephemeral, err := manager.Ephemeral()
+	if err != nil {
+		return err
+	}
+	// Keep stdout for the command's output
+	ephemeral.SetOutput(os.Stderr)
+
+	// Build Docker image if needed
+	fmt.Fprintln(os.Stderr, "Checking Docker image...")
+	if _, err := docker.BuildImageWithOptions(ctx, docker.BuildOptions{Output: os.Stderr}); err != nil {
+		return fmt.Errorf("failed to build Docker image: %w", err)
+	}
+
+	// Cancel the startup on interrupt so the container is still cleaned up
+	initCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Registered first, the container may exist even if starting it fails
+	defer removeEphemeral(ephemeral)
+
+	fmt.Fprintf(os.Stderr, "Starting container %s...\n", ephemeral.GetContainerName())
+	if err := ephemeral.CreateAndStartContainer(initCtx, cfg); err != nil {
+		return fmt.Errorf("failed to start container: %w", err)
+	}
+
+	fmt.Fprintln(os.Stderr, "Waiting for container initialization...")
+	if err := ephemeral.WaitForInitialization(initCtx); err != nil {
+		return fmt.Errorf("initialization failed: %w", err)
+	}
+
+	// From here on signals are forwarded to the command instead
+	stop()
+
+	// The command's exit code is passed through
+	err = ephemeral.ExecInteractive(ctx, docker.ExecOptions{Cmd: args})
+	warnForeignFilesAfterSession(ctx, ephemeral)
+	return err
+}
+
+func removeEphemeral(manager *docker.Manager) {
+	// The command context may already be cancelled
+	ctx := context.Background()
+	if exists, err := manager.ContainerExists(ctx); err == nil && !exists {
+		return
+	}
+
+	if runKeep {
+		fmt.Fprintf(os.Stderr, "Keeping container %s, remove it with 'docker rm -f %s'\n", manager.GetContainerName(), manager.GetContainerName())
+		return
+	}
+
+	if err := manager.StopAndRemoveContainer(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remove container %s: %v\n", manager.GetContainerName(), err)
+	}
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/common-creation/claudeway/internal/guest"
+)
+
+func TestRunRemovesContainer(t *testing.T) {
+	runtime, _ := setupProject(t, "init:\n  - exit 1\n")
+
+	// Only the command writes to stdout
+	stdout, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := os.Stdout
+	os.Stdout = stdout
+	err = runRunInternal(runCmd, []string{"true"})
+	os.Stdout = previous
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if output, _ := os.ReadFile(stdout.Name()); len(output) != 0 {
+		t.Fatalf("run wrote its own output to stdout: %q", output)
+	}
+	if names := runtime.Containers(); len(names) != 0 {
+		t.Fatalf("container was not removed after the command: %v", names)
+	}
+
+	// A container that fails to initialize is removed as well
+	runtime.StepResult = func(step guest.Step) int { return 1 }
+	if err := runRunInternal(runCmd, []string{"true"}); err == nil {
+		t.Fatal("expected run to fail when an init step fails")
+	}
+	if names := runtime.Containers(); len(names) != 0 {
+		t.Fatalf("container was not removed after a failed initialization: %v", names)
+	}
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/common-creation/claudeway/internal/config"
+	"github.com/common-creation/claudeway/internal/docker"
+	"github.com/common-creation/claudeway/internal/term"
+)
+
+// loadConfig loads the configuration. If it sets no agent, the agent the
+// project's container was created with by 'claudeway agent' is kept, so the
+// container is not taken as out of date and keeps the agent when recreated.
+// The configured command is kept as well. An agent removed from the
+// configuration is not kept, the container is out of date instead.
+func loadConfig(ctx context.Context) (*config.Config, error) {
+	cfg, err := config.Load()
+	if err != nil || cfg.AgentName() != "" {
+		return cfg, err
+	}
+
+	manager, err := docker.NewManager()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker manager: %w", err)
+	}
+	name, err := manager.ContainerAgent(ctx)
+	if err != nil || name == "" {
+		return cfg, err
+	}
+
+	agentCfg, err := config.LoadWithAgent(name)
+	if err != nil {
+		return nil, err
+	}
+	agentCfg.Command = cfg.Command
+	return agentCfg, nil
+}
+
+// enterSandbox makes sure the project's container is running and initialized,
+// creating, resuming or recreating it as needed, and then runs command in it.
+// An empty command starts a login shell. With detach, it returns once the
+// container is ready.
+func enterSandbox(ctx context.Context, cfg *config.Config, command []string, detach, recreate bool) error {
+	// Create Docker manager
+	manager, err := docker.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create docker manager: %w", err)
+	}
+	// Progress goes to stderr, stdout is the command's
+	manager.SetOutput(os.Stderr)
+
+	// Build Docker image if needed
+	fmt.Fprintln(os.Stderr, "Checking Docker image...")
+	if _, err := docker.BuildImageWithOptions(ctx, docker.BuildOptions{Output: os.Stderr}); err != nil {
+		return fmt.Errorf("failed to build Docker image: %w", err)
+	}
+
+	// Check the state of an existing container
+	exists, err := manager.ContainerExists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if container exists: %w", err)
+	}
+
+	if exists {
+		// Recreate the container if the configuration changed since it was created
+		removed, err := reconcileDrift(ctx, manager, cfg, recreate)
+		if err != nil {
+			return err
+		}
+		exists = !removed
+	}
+
+	if !exists {
+		if err := startContainer(ctx, manager, cfg); err != nil {
+			return err
+		}
+	} else {
+		running, err := manager.IsContainerRunning(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check container status: %w", err)
+		}
+
+		// Restart a stopped container instead of discarding its state
+		if !running {
+			if err := resumeContainer(ctx, manager); err != nil {
+				return err
+			}
+		}
+	}
+
+	if detach {
+		fmt.Printf("Container %s is running, use 'claudeway exec' to enter it\n", manager.GetContainerName())
+		return nil
+	}
+
+	if len(command) == 0 {
+		command = []string{"/bin/bash", "-l"}
+	}
+
+	// Exec into the container, the command's exit code is passed through
+	fmt.Fprintf(os.Stderr, "Entering container %s...\n", manager.GetContainerName())
+	err = manager.ExecInteractive(ctx, docker.ExecOptions{Cmd: command})
+	warnForeignFilesAfterSession(ctx, manager)
+	return err
+}
+
+// startContainer creates and starts a fresh container and waits for its
+// initialization. A container that fails to initialize is removed again.
+func startContainer(ctx context.Context, manager *docker.Manager, cfg *config.Config) error {
+	// Create and start container
+	fmt.Fprintln(os.Stderr, "Starting container...")
+	if err := manager.CreateAndStartContainer(ctx, cfg); err != nil {
+		return fmt.Errorf("failed to start container: %w", err)
+	}
+
+	// Wait for initialization to complete
+	fmt.Fprintln(os.Stderr, "Waiting for container initialization...")
+	if err := manager.WaitForInitialization(ctx); err != nil {
+		// If initialization failed, stop and remove the container
+		fmt.Fprintf(os.Stderr, "Initialization failed: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Cleaning up failed container...")
+		if cleanupErr := manager.StopAndRemoveContainer(ctx); cleanupErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to clean up container: %v\n", cleanupErr)
+		}
+		return err
+	}
+
+	warnForeignFiles(ctx, manager)
+	return nil
+}
+
+// resumeContainer starts a stopped container and waits for its guest agent to
+// finish. Initialization that already completed is skipped by the agent.
+func resumeContainer(ctx context.Context, manager *docker.Manager) error {
+	fmt.Fprintf(os.Stderr, "Starting stopped container %s...\n", manager.GetContainerName())
+	if err := manager.StartContainer(ctx); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "Waiting for container initialization...")
+	if err := manager.WaitForInitialization(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Initialization failed: %v\n", err)
+		fmt.Fprintln(os.Stderr, "The container was kept, use 'claudeway down' to remove it")
+		return err
+	}
+
+	return nil
+}
+
+// reconcileDrift checks whether the existing container was created from a
+// different configuration or image. If so, it shows what changed and removes
+// the container when the user agrees or force is set. It reports whether the
+// container was removed.
+func reconcileDrift(ctx context.Context, manager *docker.Manager, cfg *config.Config, force bool) (bool, error) {
+	drift, err := manager.CheckDrift(ctx, cfg)
+	if err != nil {
+		return false, fmt.Errorf("failed to check configuration drift: %w", err)
+	}
+	if drift == nil {
+		return false, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Container %s is out of date with the current configuration:\n", manager.GetContainerName())
+	for _, change := range drift.Changes {
+		fmt.Fprintf(os.Stderr, "  %s\n", change)
+	}
+
+	if !force {
+		if !term.IsTerminal(os.Stdin.Fd()) {
+			fmt.Fprintln(os.Stderr, "Keeping the existing container, use --recreate to recreate it without asking")
+			return false, nil
+		}
+		if !confirm("Recreate the container?") {
+			fmt.Fprintln(os.Stderr, "Keeping the existing container")
+			return false, nil
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Removing container %s...\n", manager.GetContainerName())
+	if err := manager.StopAndRemoveContainer(ctx); err != nil {
+		return false, fmt.Errorf("failed to remove container: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/common-creation/claudeway/internal/config"
+	"github.com/common-creation/claudeway/internal/docker"
+	"github.com/common-creation/claudeway/internal/docker/fake"
+	"github.com/common-creation/claudeway/internal/guest"
+	"github.com/docker/docker/api/types"
+)
+
+// setupProject runs the test in a fresh project directory with its own
+// configuration and state, against the fake runtime
+func setupProject(t *testing.T, yaml string) (*fake.Runtime, *config.Config) {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, ".local", "state"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "")
+
+	project := t.TempDir()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(project); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+
+	if err := os.WriteFile(filepath.Join(project, "claudeway.yaml"), []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load configuration: %v", err)
+	}
+
+	runtime := fake.New()
+	t.Cleanup(runtime.Install())
+	return runtime, cfg
+}
+
+// sessions returns the commands run through exec, without the ones the
+// manager runs itself
+func sessions(runtime *fake.Runtime) [][]string {
+	var commands [][]string
+	for _, exec := range runtime.Execs() {
+		if exec.Config.AttachStdout {
+			commands = append(commands, fake.Command(exec.Config))
+		}
+	}
+	return commands
+}
+
+func TestSandboxUpExecDown(t *testing.T) {
+	runtime, cfg := setupProject(t, "init:\n  - echo ready\n")
+	runtime.ExecHandler = func(container string, config types.ExecConfig, stdin io.Reader, stdout, stderr io.Writer) int {
+		if reflect.DeepEqual(fake.Command(config), []string{"false"}) {
+			return 3
+		}
+		return 0
+	}
+	ctx := context.Background()
+
+	if err := enterSandbox(ctx, cfg, nil, true, false); err != nil {
+		t.Fatalf("up -d failed: %v", err)
+	}
+	manager, err := docker.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := runtime.Container(manager.GetContainerName())
+	if created == nil || !created.Running {
+		t.Fatalf("container %s is not running after up", manager.GetContainerName())
+	}
+
+	if err := enterSandbox(ctx, cfg, []string{"echo", "hello"}, false, false); err != nil {
+		t.Fatalf("exec failed: %v", err)
+	}
+	if got := runtime.Container(manager.GetContainerName()); got == nil || got.ID != created.ID {
+		t.Fatal("the running container was not reused")
+	}
+
+	var exitErr *docker.ExitError
+	err = enterSandbox(ctx, cfg, []string{"false"}, false, false)
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("expected exit code 3 to be passed through, got %v", err)
+	}
+
+	want := [][]string{{"echo", "hello"}, {"false"}}
+	if got := sessions(runtime); !reflect.DeepEqual(got, want) {
+		t.Fatalf("sessions = %q, want %q", got, want)
+	}
+
+	if err := runDownInternal(downCmd, nil); err != nil {
+		t.Fatalf("down failed: %v", err)
+	}
+	if runtime.Container(manager.GetContainerName()) != nil {
+		t.Fatal("container still exists after down")
+	}
+}
+
+func TestSandboxInitFailure(t *testing.T) {
+	runtime, cfg := setupProject(t, "init:\n  - echo ready\n  - exit 1\n")
+	runtime.StepResult = func(step guest.Step) int {
+		if step.Name == "exit 1" {
+			return 1
+		}
+		return 0
+	}
+
+	if err := enterSandbox(context.Background(), cfg, nil, true, false); err == nil {
+		t.Fatal("expected up to fail when an init step fails")
+	}
+	if names := runtime.Containers(); len(names) != 0 {
+		t.Fatalf("failed container was not removed: %v", names)
+	}
+	if got := sessions(runtime); len(got) != 0 {
+		t.Fatalf("commands ran in a container that failed to initialize: %q", got)
+	}
+}
+
+func TestLoadConfigKeepsAgentFromCommandLine(t *testing.T) {
+	setupProject(t, "")
+	ctx := context.Background()
+
+	cfg, err := config.LoadWithAgent("claude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enterSandbox(ctx, cfg, nil, true, false); err != nil {
+		t.Fatalf("agent failed: %v", err)
+	}
+
+	cfg, err = loadConfig(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AgentName() != "claude" {
+		t.Fatalf("agent = %q, want the one the container was started with", cfg.AgentName())
+	}
+	manager, err := docker.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drift, err := manager.CheckDrift(ctx, cfg); err != nil || drift != nil {
+		t.Fatalf("container started by 'claudeway agent' is out of date: %v %v", drift, err)
+	}
+}
+
+func TestLoadConfigDropsAgentRemovedFromConfig(t *testing.T) {
+	_, cfg := setupProject(t, "agent: claude\n")
+	ctx := context.Background()
+
+	if err := enterSandbox(ctx, cfg, nil, true, false); err != nil {
+		t.Fatalf("up failed: %v", err)
+	}
+	if err := os.WriteFile("claudeway.yaml", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AgentName() != "" {
+		t.Fatalf("agent %q removed from claudeway.yaml was kept", cfg.AgentName())
+	}
+	manager, err := docker.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	drift, err := manager.CheckDrift(ctx, cfg)
+	if err != nil || drift == nil {
+		t.Fatalf("expected the removed agent to be reported as drift, got %v %v", drift, err)
+	}
+}
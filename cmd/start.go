@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/common-creation/claudeway/internal/docker"
+)
+
+var startCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start a stopped claudeway container",
+	Long: `Start the stopped claudeway container for the current directory without entering it.
+Initialization that already completed is not repeated.`,
+	RunE:          runStart,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var startRecreate bool
+
+func init() {
+	startCmd.Flags().BoolVar(&startRecreate, "recreate", false, "Recreate the container without asking if its configuration is out of date")
+	rootCmd.AddCommand(startCmd)
+}
+
+func runStart(cmd *cobra.Command, args []string) error {
+	if err := runStartInternal(cmd, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runStartInternal(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Load configuration
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Create Docker manager
+	manager, err := docker.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create docker manager: %w", err)
+	}
+
+	exists, err := manager.ContainerExists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if container exists: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("no container found for the current directory. Use 'claudeway up' to create one")
+	}
+
+	running, err := manager.IsContainerRunning(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if running {
+		fmt.Printf("Container %s is already running\n", manager.GetContainerName())
+		return nil
+	}
+
+	// Recreate the container if the configuration changed since it was created
+	removed, err := reconcileDrift(ctx, manager, cfg, startRecreate)
+	if err != nil {
+		return err
+	}
+
+	if removed {
+		fmt.Println("Checking Docker image...")
+		if err := docker.BuildDockerImage(); err != nil {
+			return fmt.Errorf("failed to build Docker image: %w", err)
+		}
+		if err := startContainer(ctx, manager, cfg); err != nil {
+			return err
+		}
+	} else if err := resumeContainer(ctx, manager); err != nil {
+		return err
+	}
+
+	fmt.Printf("Container %s is running\n", manager.GetContainerName())
+	return nil
+}
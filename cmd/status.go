@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/common-creation/claudeway/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of the claudeway container",
+	Long: `Show the state, initialization result, mounts and live resource usage
+of the claudeway container for the current directory.`,
+	RunE:          runStatus,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var (
+	statusWatch    bool
+	statusInterval time.Duration
+	statusFormat   string
+)
+
+func init() {
+	statusCmd.Flags().BoolVarP(&statusWatch, "watch", "w", false, "Refresh the status continuously")
+	statusCmd.Flags().DurationVar(&statusInterval, "interval", 2*time.Second, "Refresh interval for --watch")
+	statusCmd.Flags().StringVar(&statusFormat, "format", "text", "Output format: text or json")
+	rootCmd.AddCommand(statusCmd)
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	if err := runStatusInternal(cmd, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runStatusInternal(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if statusFormat != "text" && statusFormat != "json" {
+		return fmt.Errorf("unknown format %q, expected text or json", statusFormat)
+	}
+
+	// Create Docker manager
+	manager, err := docker.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create docker manager: %w", err)
+	}
+
+	for {
+		status, err := manager.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		if statusFormat == "json" {
+			// One JSON document per line, so --watch output can be consumed line by line
+			data, err := json.Marshal(status)
+			if err != nil {
+				return fmt.Errorf("failed to marshal status: %w", err)
+			}
+			fmt.Println(string(data))
+		} else {
+			if statusWatch {
+				// Move the cursor home and clear the screen to refresh in place
+				fmt.Print("\033[H\033[2J")
+			}
+			printStatus(status)
+		}
+
+		if !statusWatch {
+			return nil
+		}
+		time.Sleep(statusInterval)
+	}
+}
+
+func printStatus(status *docker.Status) {
+	fmt.Printf("Container:  %s\n", status.Name)
+	fmt.Printf("Project:    %s\n", status.Project)
+	fmt.Printf("Runtime:    %s on %s\n", status.Runtime, status.Endpoint)
+
+	if !status.Exists {
+		fmt.Println("State:      not created (use 'claudeway up' to start one)")
+		return
+	}
+
+	state := status.State
+	if status.Running {
+		if status.StartedAt != nil {
+			state += fmt.Sprintf(" (up %s)", time.Since(*status.StartedAt).Round(time.Second))
+		}
+	} else {
+		state += fmt.Sprintf(" (exit code %d)", status.ExitCode)
+	}
+	if status.OOMKilled {
+		state += ", OOM killed"
+	}
+	fmt.Printf("State:      %s\n", state)
+	initState := status.Init
+	// Show the step that is running or failed
+	if n := len(status.InitSteps); n > 0 && status.Init != docker.InitComplete {
+		step := status.InitSteps[n-1]
+		initState += fmt.Sprintf(" (step %d: %s)", step.Number, step.Label())
+	}
+	fmt.Printf("Init:       %s\n", initState)
+	if user := status.User; user != nil {
+		line := fmt.Sprintf("%s (%d:%d, group %s)", user.Name, user.UID, user.GID, user.Group)
+		for i, group := range user.Groups {
+			if i == 0 {
+				line += ", groups "
+			} else {
+				line += " "
+			}
+			line += group.Name
+		}
+		fmt.Printf("User:       %s\n", line)
+	}
+	if status.Workspace != "" {
+		fmt.Printf("Workspace:  %s\n", status.Workspace)
+	}
+	fmt.Printf("Network:    %s\n", status.NetworkMode)
+
+	if len(status.Mounts) > 0 {
+		fmt.Println("Mounts:")
+		for _, mount := range status.Mounts {
+			line := fmt.Sprintf("  %-6s %s -> %s", mount.Type, mount.Source, mount.Target)
+			if mount.ReadOnly {
+				line += " (ro)"
+			}
+			fmt.Println(line)
+		}
+	}
+
+	if usage := status.Resources; usage != nil {
+		fmt.Printf("CPU:        %.2f%%\n", usage.CPUPercent)
+		fmt.Printf("Memory:     %s / %s (%.2f%%)\n", formatBytes(usage.MemoryUsage), formatBytes(usage.MemoryLimit), usage.MemoryPercent)
+		fmt.Printf("Net I/O:    %s / %s\n", formatBytes(usage.NetworkRx), formatBytes(usage.NetworkTx))
+		fmt.Printf("Block I/O:  %s / %s\n", formatBytes(usage.BlockRead), formatBytes(usage.BlockWrite))
+		fmt.Printf("PIDs:       %d\n", usage.PIDs)
+	}
+}
+
+// formatBytes renders a byte count with a binary unit suffix
+func formatBytes(n uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(n)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d%s", n, units[unit])
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%.2f", value), ".00") + units[unit]
+}
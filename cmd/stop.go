@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/common-creation/claudeway/internal/docker"
+)
+
+var stopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the claudeway container without removing it",
+	Long: `Stop the running claudeway container for the current directory but keep it,
+so 'claudeway start' or 'claudeway up' can resume it without repeating the initialization.`,
+	RunE:          runStop,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	rootCmd.AddCommand(stopCmd)
+}
+
+func runStop(cmd *cobra.Command, args []string) error {
+	if err := runStopInternal(cmd, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runStopInternal(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Create Docker manager
+	manager, err := docker.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create docker manager: %w", err)
+	}
+
+	// Check if container is running
+	running, err := manager.IsContainerRunning(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+
+	if !running {
+		fmt.Println("No running container found for the current directory")
+		return nil
+	}
+
+	fmt.Printf("Stopping container %s...\n", manager.GetContainerName())
+	if err := manager.StopContainer(ctx); err != nil {
+		return err
+	}
+
+	fmt.Println("Container stopped")
+	return nil
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/common-creation/claudeway/internal/docker"
+	"github.com/common-creation/claudeway/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Sync the workspace with a container on a remote daemon",
+	Long: `Sync changes between the project directory and the workspace volume of the
+container in both directions. Containers on remote daemons, or with
+'workspace: sync', keep the workspace in a volume instead of bind-mounting it.
+Sessions sync it while they run, this command syncs it in between, e.g. after
+'claudeway up -d' or 'claudeway task'.`,
+	RunE:          runSync,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var syncWatch bool
+
+func init() {
+	syncCmd.Flags().BoolVarP(&syncWatch, "watch", "w", false, "Keep syncing until interrupted")
+	rootCmd.AddCommand(syncCmd)
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	if err := runSyncInternal(cmd, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runSyncInternal(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	manager, err := docker.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create docker manager: %w", err)
+	}
+
+	synced, err := manager.SyncsWorkspace(ctx)
+	if err != nil {
+		return err
+	}
+	if !synced {
+		fmt.Println("The workspace of this project is bind-mounted, there is nothing to sync")
+		return nil
+	}
+	running, err := manager.IsContainerRunning(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("no running container found for the current directory. Use 'claudeway up -d' to start one")
+	}
+
+	summary := &docker.SyncSummary{}
+	if syncWatch {
+		fmt.Println("Syncing the workspace, press Ctrl-C to stop")
+		watchCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		summary, err = manager.WatchWorkspace(watchCtx)
+	} else {
+		var plan *workspace.SyncPlan
+		if plan, err = manager.SyncWorkspace(ctx); err == nil {
+			summary.Add(plan)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to sync the workspace: %w", err)
+	}
+
+	if summary.Pushed+summary.Pulled+summary.Deleted == 0 {
+		fmt.Println("The workspace is in sync")
+		return nil
+	}
+	summary.Print(os.Stdout)
+	return nil
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/common-creation/claudeway/internal/config"
+	"github.com/common-creation/claudeway/internal/docker"
+	"github.com/common-creation/claudeway/internal/task"
+	"github.com/spf13/cobra"
+)
+
+var taskCmd = &cobra.Command{
+	Use:   "task [flags] [-- command...]",
+	Short: "Run the coding agent headlessly against a prompt",
+	Long: `Start the claudeway container and run the configured agent non-interactively,
+passing it the prompt from --prompt-file or stdin. A command given after '--' is
+run instead of the agent.
+
+Progress is written to stderr. When the run ends, a JSON report with the status,
+exit code, duration, transcript path and a unified diff of the workspace changes
+is written to stdout (or --report), and the transcript, patch and report are saved
+in the output directory. claudeway exits with 1 unless the run succeeded.
+
+The workspace must be a git repository to compute the diff.`,
+	RunE:          runTask,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var (
+	taskPromptFile string
+	taskTimeout    time.Duration
+	taskEphemeral  bool
+	taskWorktree   bool
+	taskAgent      string
+	taskOutput     string
+	taskReport     string
+	taskName       string
+)
+
+func init() {
+	// Flags after the command belong to the command
+	taskCmd.Flags().SetInterspersed(false)
+	taskCmd.Flags().StringVar(&taskPromptFile, "prompt-file", "-", "File to read the prompt from, '-' for stdin")
+	taskCmd.Flags().DurationVar(&taskTimeout, "timeout", 30*time.Minute, "Stop the agent after this duration, 0 to disable")
+	taskCmd.Flags().BoolVar(&taskEphemeral, "ephemeral", false, "Run in a throwaway container that is removed afterwards")
+	taskCmd.Flags().BoolVar(&taskWorktree, "worktree", false, "Run in a temporary git worktree at HEAD instead of the project directory (implies --ephemeral)")
+	taskCmd.Flags().StringVar(&taskAgent, "agent", "", "Agent preset to run instead of the configured one")
+	taskCmd.Flags().StringVarP(&taskOutput, "output", "o", "", "Directory for the transcript, patch and report (default: a new directory in the XDG state directory)")
+	taskCmd.Flags().StringVar(&taskReport, "report", "-", "File to write the JSON report to, '-' for stdout")
+	taskCmd.Flags().StringVar(&taskName, "name", "", "Name of the task, used in the report and the default output directory")
+	rootCmd.AddCommand(taskCmd)
+}
+
+func runTask(cmd *cobra.Command, args []string) error {
+	if err := runTaskInternal(cmd, args); err != nil {
+		exitWithError(err)
+	}
+	return nil
+}
+
+func runTaskInternal(cmd *cobra.Command, args []string) error {
+	// Load configuration with the requested agent
+	cfg, err := config.LoadWithAgent(taskAgent)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	var prompt string
+	if len(args) == 0 {
+		prompt, err = readPrompt(taskPromptFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	workDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	// Cancel the run on interrupt so the sandbox is still cleaned up
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Keep stdout for the report, image build output goes to stderr
+	fmt.Fprintln(os.Stderr, "Checking Docker image...")
+	if _, err := docker.BuildImageWithOptions(ctx, docker.BuildOptions{Output: os.Stderr}); err != nil {
+		return fmt.Errorf("failed to build Docker image: %w", err)
+	}
+
+	outputDir := taskOutput
+	if outputDir == "" {
+		outputDir = task.DefaultOutputDir(taskName)
+	}
+
+	report := task.Run(ctx, cfg, workDir, task.Spec{
+		Name:      taskName,
+		Prompt:    prompt,
+		Command:   args,
+		Timeout:   taskTimeout,
+		Ephemeral: taskEphemeral,
+		Worktree:  taskWorktree,
+		OutputDir: outputDir,
+		Log:       os.Stderr,
+	})
+
+	if err := writeTaskReport(taskReport, os.Stdout, report); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Task %s, output saved to %s\n", report.Status, outputDir)
+
+	if report.Status != task.StatusSuccess {
+		return &docker.ExitError{Code: 1}
+	}
+	return nil
+}
+
+func readPrompt(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt: %w", err)
+	}
+	if len(data) == 0 {
+		return "", fmt.Errorf("prompt is empty")
+	}
+	return string(data), nil
+}
+
+func writeTaskReport(path string, stdout *os.File, report *task.Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if path == "-" {
+		_, err = stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Package agent holds the built-in presets describing how to install and
+// launch coding agents inside a claudeway sandbox.
+package agent
+
+import "sort"
+
+// Preset describes a coding agent
+type Preset struct {
+	Name        string
+	Description string
+	// Install are init commands installing the agent. They must be safe to
+	// run when the agent or its runtime is already installed.
+	Install []string
+	// Bind are credential and config paths shared with the host. Paths that
+	// do not exist on the host are skipped.
+	Bind []string
+	// Command launches the agent interactively
+	Command []string
+	// SandboxArgs are flags that are only reasonable inside the sandbox,
+	// such as skipping permission prompts
+	SandboxArgs []string
+	// Headless runs the agent non-interactively with the prompt on stdin
+	Headless []string
+}
+
+// installNode installs Node.js with asdf unless npm is already available
+const installNode = "command -v npm >/dev/null 2>&1 || (asdf install nodejs 22.17.0 && asdf global nodejs 22.17.0)"
+
+var presets = map[string]*Preset{
+	"claude": {
+		Name:        "claude",
+		Description: "Claude Code by Anthropic",
+		Install: []string{
+			installNode,
+			"command -v claude >/dev/null 2>&1 || npm i -g @anthropic-ai/claude-code",
+		},
+		Bind: []string{
+			"~/.claude.json:~/.claude.json",
+			"~/.claude:~/.claude",
+		},
+		Command:     []string{"claude"},
+		SandboxArgs: []string{"--dangerously-skip-permissions"},
+		Headless:    []string{"claude", "--print", "--dangerously-skip-permissions"},
+	},
+	"codex": {
+		Name:        "codex",
+		Description: "Codex CLI by OpenAI",
+		Install: []string{
+			installNode,
+			"command -v codex >/dev/null 2>&1 || npm i -g @openai/codex",
+		},
+		Bind: []string{
+			"~/.codex:~/.codex",
+		},
+		Command:     []string{"codex"},
+		SandboxArgs: []string{"--dangerously-bypass-approvals-and-sandbox"},
+		Headless:    []string{"codex", "exec", "--dangerously-bypass-approvals-and-sandbox", "-"},
+	},
+	"gemini": {
+		Name:        "gemini",
+		Description: "Gemini CLI by Google",
+		Install: []string{
+			installNode,
+			"command -v gemini >/dev/null 2>&1 || npm i -g @google/gemini-cli",
+		},
+		Bind: []string{
+			"~/.gemini:~/.gemini",
+		},
+		Command:     []string{"gemini"},
+		SandboxArgs: []string{"--yolo"},
+		Headless:    []string{"gemini", "--yolo"},
+	},
+	"aider": {
+		Name:        "aider",
+		Description: "Aider AI pair programming",
+		Install: []string{
+			"command -v aider >/dev/null 2>&1 || (apt-get update && apt-get install -y pipx && PIPX_HOME=/opt/pipx PIPX_BIN_DIR=/usr/local/bin pipx install aider-chat)",
+		},
+		Bind: []string{
+			"~/.aider.conf.yml:~/.aider.conf.yml",
+		},
+		Command:     []string{"aider"},
+		SandboxArgs: []string{"--yes-always"},
+		Headless:    []string{"aider", "--yes-always", "--message-file", "/dev/stdin"},
+	},
+}
+
+// Lookup returns the preset with the given name
+func Lookup(name string) (*Preset, bool) {
+	preset, ok := presets[name]
+	return preset, ok
+}
+
+// Names returns the names of all presets in sorted order
+func Names() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LaunchCommand returns the command line starting the agent interactively
+func (p *Preset) LaunchCommand(sandboxed bool, args []string) []string {
+	command := append([]string{}, p.Command...)
+	if sandboxed {
+		command = append(command, p.SandboxArgs...)
+	}
+	return append(command, args...)
+}
@@ -6,6 +6,3 @@ import (
 
 //go:embed Dockerfile
 var DockerfileContent string
-
-//go:embed entrypoint.sh
-var EntrypointContent string
\ No newline at end of file
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/common-creation/claudeway/internal/agent"
+	"gopkg.in/yaml.v3"
+)
+
+// Agent selects a built-in agent preset, which adds the agent's install
+// commands and credential binds and makes it the default command
+type Agent struct {
+	Name string `yaml:"name" json:"name"`
+	// Sandboxed enables flags that are only safe inside the sandbox, such as
+	// skipping permission prompts. Defaults to true.
+	Sandboxed *bool `yaml:"sandboxed,omitempty" json:"sandboxed,omitempty"`
+	// Args are appended to the agent's command line
+	Args []string `yaml:"args,omitempty" json:"args,omitempty"`
+}
+
+// UnmarshalYAML accepts either a preset name or a mapping
+func (a *Agent) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		a.Name = value.Value
+		return nil
+	}
+	type plain Agent
+	return value.Decode((*plain)(a))
+}
+
+// AgentName returns the name of the agent preset, empty if there is none
+func (c *Config) AgentName() string {
+	if c.Agent == nil {
+		return ""
+	}
+	return c.Agent.Name
+}
+
+// AgentOverridden reports whether the agent was named on the command line,
+// such as by 'claudeway agent', instead of in the configuration
+func (c *Config) AgentOverridden() bool {
+	return c.agentOverridden
+}
+
+// IsSandboxed reports whether sandbox-only flags should be used
+func (a *Agent) IsSandboxed() bool {
+	return a.Sandboxed == nil || *a.Sandboxed
+}
+
+// Preset returns the built-in preset the agent refers to
+func (a *Agent) Preset() (*agent.Preset, error) {
+	preset, ok := agent.Lookup(a.Name)
+	if !ok {
+		return nil, fmt.Errorf("unknown agent %q (available: %s)", a.Name, strings.Join(agent.Names(), ", "))
+	}
+	return preset, nil
+}
+
+// applyAgent expands the configured agent preset into init commands, binds
+// and the default command
+func (c *Config) applyAgent() error {
+	if c.Agent == nil || c.Agent.Name == "" {
+		return nil
+	}
+
+	preset, err := c.Agent.Preset()
+	if err != nil {
+		return err
+	}
+
+	// Agent installation runs after the project's own init commands, so a
+	// runtime installed by them is reused. Agents are installed system wide.
+	for _, command := range preset.Install {
+		c.Init = append(c.Init, InitStep{Run: command, User: InitUserRoot})
+	}
+	c.agentSteps = len(preset.Install)
+
+	bindMap := make(map[string]bool)
+	for _, bind := range c.Bind {
+		bindMap[bind] = true
+	}
+	for _, bind := range preset.Bind {
+		if bindMap[bind] || !hostPathExists(strings.SplitN(bind, ":", 2)[0]) {
+			continue
+		}
+		c.Bind = append(c.Bind, bind)
+		c.agentBinds++
+	}
+
+	if len(c.Command) == 0 {
+		c.Command = preset.LaunchCommand(c.Agent.IsSandboxed(), c.Agent.Args)
+	}
+	return nil
+}
+
+func hostPathExists(path string) bool {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return false
+		}
+		path = filepath.Join(home, path[2:])
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
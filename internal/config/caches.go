@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Cache is a claudeway-managed volume shared across projects and mounted at
+// Path inside the container. Paths starting with ~/ refer to the home
+// directory of the container user.
+type Cache struct {
+	Name string `yaml:"name" json:"name"`
+	Path string `yaml:"path" json:"path"`
+}
+
+// CachePresets are the built-in caches that can be referenced by name
+var CachePresets = map[string]string{
+	"asdf":     "/opt/asdf/installs",
+	"npm":      "~/.npm",
+	"yarn":     "~/.cache/yarn",
+	"pnpm":     "~/.local/share/pnpm/store",
+	"go":       "~/go/pkg/mod",
+	"go-build": "~/.cache/go-build",
+	"pip":      "~/.cache/pip",
+	"cargo":    "~/.cargo/registry",
+}
+
+// CachePresetNames returns the names of the built-in caches in sorted order
+func CachePresetNames() []string {
+	names := make([]string, 0, len(CachePresets))
+	for name := range CachePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnmarshalYAML accepts either a preset name or a mapping with name and path
+func (c *Cache) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		c.Name = value.Value
+		if strings.HasPrefix(c.Name, "#") {
+			// Comment entry, ignored like comments in the other sections
+			return nil
+		}
+		path, ok := CachePresets[c.Name]
+		if !ok {
+			return fmt.Errorf("unknown cache preset %q (available: %s)", c.Name, strings.Join(CachePresetNames(), ", "))
+		}
+		c.Path = path
+		return nil
+	}
+
+	type plain Cache
+	if err := value.Decode((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Name == "" {
+		return fmt.Errorf("cache entry at line %d has no name", value.Line)
+	}
+	if c.Path == "" {
+		path, ok := CachePresets[c.Name]
+		if !ok {
+			return fmt.Errorf("cache %q has no path and is not a preset", c.Name)
+		}
+		c.Path = path
+	}
+	return nil
+}
+
+// MarshalYAML writes preset caches back as their name
+func (c Cache) MarshalYAML() (interface{}, error) {
+	if path, ok := CachePresets[c.Name]; (ok && path == c.Path) || strings.HasPrefix(c.Name, "#") {
+		return c.Name, nil
+	}
+	type plain Cache
+	return plain(c), nil
+}
+
+// String renders the cache for diffs and listings
+func (c Cache) String() string {
+	return fmt.Sprintf("%s (%s)", c.Name, c.Path)
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestCacheYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    []Cache
+		wantErr string
+	}{
+		{
+			name: "presets and custom caches",
+			yaml: "caches:\n  - npm\n  - name: go\n  - name: tools\n    path: /opt/tools\n",
+			want: []Cache{
+				{Name: "npm", Path: "~/.npm"},
+				{Name: "go", Path: "~/go/pkg/mod"},
+				{Name: "tools", Path: "/opt/tools"},
+			},
+		},
+		{
+			name: "comment",
+			yaml: "caches:\n  - '# none yet'\n",
+			want: []Cache{{Name: "# none yet"}},
+		},
+		{
+			name:    "unknown preset",
+			yaml:    "caches:\n  - maven\n",
+			wantErr: `unknown cache preset "maven"`,
+		},
+		{
+			name:    "no name",
+			yaml:    "caches:\n  - path: /opt/tools\n",
+			wantErr: "has no name",
+		},
+		{
+			name:    "no path",
+			yaml:    "caches:\n  - name: tools\n",
+			wantErr: `cache "tools" has no path`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			err := yaml.Unmarshal([]byte(tt.yaml), &cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg.Caches, tt.want) {
+				t.Errorf("caches = %+v, want %+v", cfg.Caches, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheMarshalYAML(t *testing.T) {
+	caches := []Cache{
+		{Name: "npm", Path: "~/.npm"},
+		{Name: "npm", Path: "/srv/npm"},
+		{Name: "tools", Path: "/opt/tools"},
+	}
+	data, err := yaml.Marshal(caches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "- npm\n- name: npm\n  path: /srv/npm\n- name: tools\n  path: /opt/tools\n"
+	if string(data) != want {
+		t.Errorf("yaml = %q, want %q", data, want)
+	}
+}
@@ -4,17 +4,131 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Init []string `yaml:"init,omitempty"`
-	Bind []string `yaml:"bind,omitempty"`
-	Copy []string `yaml:"copy,omitempty"`
+	Init []InitStep `yaml:"init,omitempty" json:"init,omitempty"`
+	// InitCache is read from 'init: {cache: ..., steps: [...]}'
+	InitCache string   `yaml:"-" json:"init_cache,omitempty"`
+	Bind      []string `yaml:"bind,omitempty" json:"bind,omitempty"`
+	Copy      []string `yaml:"copy,omitempty" json:"copy,omitempty"`
+
+	Caches []Cache `yaml:"caches,omitempty" json:"caches,omitempty"`
+
+	// Command is what 'claudeway up' launches in the container, a login
+	// shell if empty
+	Command Command `yaml:"command,omitempty" json:"command,omitempty"`
+
+	// Agent is a built-in agent preset to install and launch
+	Agent *Agent `yaml:"agent,omitempty" json:"agent,omitempty"`
+
+	// Workspace selects between bind-mounting and syncing the project
+	Workspace *Workspace `yaml:"workspace,omitempty" json:"workspace,omitempty"`
+
+	// agentSteps and agentBinds count the init steps and binds the agent
+	// preset appended
+	agentSteps int
+	agentBinds int
+	// agentOverridden is set if the agent was named on the command line
+	agentOverridden bool
+}
+
+// Command is a command line given either as a list of arguments or as a
+// single string that is run by bash
+type Command []string
+
+// UnmarshalYAML accepts a string or a list of strings
+func (c *Command) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if value.Value == "" {
+			*c = nil
+			return nil
+		}
+		*c = Command{"/bin/bash", "-lc", value.Value}
+		return nil
+	}
+
+	var args []string
+	if err := value.Decode(&args); err != nil {
+		return err
+	}
+	*c = args
+	return nil
+}
+
+// UnmarshalYAML accepts init either as a list of steps or as a mapping with
+// the steps, the cache mode and the default user of the steps
+func (c *Config) UnmarshalYAML(value *yaml.Node) error {
+	initUser := ""
+	if value.Kind == yaml.MappingNode {
+		content := append([]*yaml.Node{}, value.Content...)
+		for i := 0; i+1 < len(content); i += 2 {
+			if content[i].Value != "init" || content[i+1].Kind != yaml.MappingNode {
+				continue
+			}
+
+			var section struct {
+				Cache string    `yaml:"cache"`
+				User  string    `yaml:"user"`
+				Steps yaml.Node `yaml:"steps"`
+			}
+			if err := content[i+1].Decode(&section); err != nil {
+				return err
+			}
+			switch section.Cache {
+			case "", "none", InitCacheImage:
+			default:
+				return fmt.Errorf("init cache must be %q or \"none\", got %q", InitCacheImage, section.Cache)
+			}
+			if section.Cache == InitCacheImage {
+				c.InitCache = section.Cache
+			}
+			switch section.User {
+			case "", InitUserRoot, InitUserHost:
+				initUser = section.User
+			default:
+				return fmt.Errorf("init user must be %q or %q, got %q", InitUserRoot, InitUserHost, section.User)
+			}
+
+			// Decode the steps as if they were the init list
+			steps := &section.Steps
+			if steps.Kind == 0 {
+				steps = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			}
+			content[i+1] = steps
+		}
+		node := *value
+		node.Content = content
+		value = &node
+	}
+
+	type plain Config
+	if err := value.Decode((*plain)(c)); err != nil {
+		return err
+	}
+
+	// Steps without a user of their own run as the section's user
+	if initUser != "" {
+		for i := range c.Init {
+			if c.Init[i].User == "" && !c.Init[i].IsComment() {
+				c.Init[i].User = initUser
+			}
+		}
+	}
+	return nil
 }
 
 func Load() (*Config, error) {
+	return LoadWithAgent("")
+}
+
+// LoadWithAgent loads the configuration like Load, but uses the named agent
+// preset instead of the configured one. An empty name keeps the configured
+// agent.
+func LoadWithAgent(agentName string) (*Config, error) {
 	globalConfig, err := loadGlobalConfig()
 	if err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("failed to load global config: %w", err)
@@ -25,7 +139,16 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to load local config: %w", err)
 	}
 
-	return mergeConfigs(globalConfig, localConfig), nil
+	config := mergeConfigs(globalConfig, localConfig)
+	if agentName != "" && (config.Agent == nil || config.Agent.Name != agentName) {
+		config.Agent = &Agent{Name: agentName}
+	}
+	config.agentOverridden = agentName != ""
+	if err := config.applyAgent(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
 }
 
 func loadGlobalConfig() (*Config, error) {
@@ -63,7 +186,7 @@ func mergeConfigs(global, local *Config) *Config {
 	}
 
 	merged := &Config{}
-	
+
 	// Global config takes precedence for init commands
 	if len(global.Init) > 0 {
 		merged.Init = append(merged.Init, global.Init...)
@@ -72,6 +195,11 @@ func mergeConfigs(global, local *Config) *Config {
 		merged.Init = append(merged.Init, local.Init...)
 	}
 
+	merged.InitCache = global.InitCache
+	if local.InitCache != "" {
+		merged.InitCache = local.InitCache
+	}
+
 	// Merge bind directories
 	bindMap := make(map[string]bool)
 	for _, bind := range global.Bind {
@@ -96,16 +224,44 @@ func mergeConfigs(global, local *Config) *Config {
 		}
 	}
 
+	// Merge caches, the local config wins for caches with the same name
+	cacheIndex := make(map[string]int)
+	for _, cache := range global.Caches {
+		cacheIndex[cache.Name] = len(merged.Caches)
+		merged.Caches = append(merged.Caches, cache)
+	}
+	for _, cache := range local.Caches {
+		if i, ok := cacheIndex[cache.Name]; ok {
+			merged.Caches[i] = cache
+			continue
+		}
+		merged.Caches = append(merged.Caches, cache)
+	}
+
+	// The local command and agent replace the global ones
+	merged.Command = global.Command
+	if len(local.Command) > 0 {
+		merged.Command = local.Command
+	}
+	merged.Agent = global.Agent
+	if local.Agent != nil {
+		merged.Agent = local.Agent
+	}
+	merged.Workspace = global.Workspace
+	if local.Workspace != nil {
+		merged.Workspace = local.Workspace
+	}
+
 	return merged
 }
 
 func CreateDefaultConfig(path string) error {
 	defaultConfig := &Config{
-		Init: []string{
+		Init: InitSteps(
 			"# Example initialization commands",
 			"# npm ci",
 			"# go mod download",
-		},
+		),
 		Bind: []string{
 			"# Example additional bind mounts",
 			"# /opt/bin",
@@ -114,6 +270,10 @@ func CreateDefaultConfig(path string) error {
 			"# Example files to copy",
 			"# ~/.zshrc",
 		},
+		Caches: []Cache{
+			{Name: "# Example shared caches (presets: " + strings.Join(CachePresetNames(), ", ") + ")"},
+			{Name: "# npm"},
+		},
 	}
 
 	data, err := yaml.Marshal(defaultConfig)
@@ -126,4 +286,4 @@ func CreateDefaultConfig(path string) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Canonical returns a copy of the config with comment entries removed, so that
+// two configs that produce the same container compare equal. Command is left
+// out since it does not affect the container itself. The agent preset is
+// recorded by name instead of the steps and binds it adds, which depend on
+// the host.
+func (c *Config) Canonical() *Config {
+	canonical := &Config{
+		Init:      initWithoutComments(c.Init[:len(c.Init)-c.agentSteps]),
+		InitCache: c.InitCache,
+		Bind:      withoutComments(c.Bind[:len(c.Bind)-c.agentBinds]),
+		Copy:      withoutComments(c.Copy),
+
+		Caches: cachesWithoutComments(c.Caches),
+	}
+	if name := c.AgentName(); name != "" {
+		canonical.Agent = &Agent{Name: name}
+	}
+	// Only the mode affects the container, auto is left out so existing
+	// containers keep their hash
+	if mode := c.WorkspaceMode(); mode != WorkspaceAuto {
+		canonical.Workspace = &Workspace{Mode: mode}
+	}
+	return canonical
+}
+
+// Hash returns a stable hash of the canonical config combined with the given
+// image ID.
+func (c *Config) Hash(imageID string) string {
+	data, err := json.Marshal(c.Canonical())
+	if err != nil {
+		// Config only contains plain values, so this cannot happen
+		panic(err)
+	}
+
+	hash := sha256.New()
+	hash.Write(data)
+	hash.Write([]byte("\n"))
+	hash.Write([]byte(imageID))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Diff returns human-readable lines describing how newConfig differs from
+// oldConfig. It returns nil if both configs are equivalent.
+func Diff(oldConfig, newConfig *Config) []string {
+	oldConfig = oldConfig.Canonical()
+	newConfig = newConfig.Canonical()
+
+	var lines []string
+	lines = append(lines, diffList("init", initStrings(oldConfig.Init), initStrings(newConfig.Init))...)
+	if oldConfig.InitCache != newConfig.InitCache {
+		lines = append(lines, fmt.Sprintf("init cache: %q -> %q", oldConfig.InitCache, newConfig.InitCache))
+	}
+	lines = append(lines, diffList("bind", oldConfig.Bind, newConfig.Bind)...)
+	lines = append(lines, diffList("copy", oldConfig.Copy, newConfig.Copy)...)
+	lines = append(lines, diffList("caches", cacheStrings(oldConfig.Caches), cacheStrings(newConfig.Caches))...)
+	if oldConfig.AgentName() != newConfig.AgentName() {
+		lines = append(lines, fmt.Sprintf("agent: %q -> %q", oldConfig.AgentName(), newConfig.AgentName()))
+	}
+	if oldConfig.WorkspaceMode() != newConfig.WorkspaceMode() {
+		lines = append(lines, fmt.Sprintf("workspace: %s -> %s", oldConfig.WorkspaceMode(), newConfig.WorkspaceMode()))
+	}
+	return lines
+}
+
+func diffList(section string, oldItems, newItems []string) []string {
+	if strings.Join(oldItems, "\x00") == strings.Join(newItems, "\x00") {
+		return nil
+	}
+
+	oldSet := make(map[string]bool)
+	for _, item := range oldItems {
+		oldSet[item] = true
+	}
+	newSet := make(map[string]bool)
+	for _, item := range newItems {
+		newSet[item] = true
+	}
+
+	var lines []string
+	for _, item := range oldItems {
+		if !newSet[item] {
+			lines = append(lines, fmt.Sprintf("%s: - %s", section, item))
+		}
+	}
+	for _, item := range newItems {
+		if !oldSet[item] {
+			lines = append(lines, fmt.Sprintf("%s: + %s", section, item))
+		}
+	}
+
+	// Same entries in a different order still matter for init commands
+	if len(lines) == 0 {
+		lines = append(lines, fmt.Sprintf("%s: order changed", section))
+	}
+	return lines
+}
+
+func withoutComments(items []string) []string {
+	var result []string
+	for _, item := range items {
+		if !strings.HasPrefix(item, "#") {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func initWithoutComments(steps []InitStep) []InitStep {
+	var result []InitStep
+	for _, step := range steps {
+		if !step.IsComment() {
+			result = append(result, step)
+		}
+	}
+	return result
+}
+
+func initStrings(steps []InitStep) []string {
+	var result []string
+	for _, step := range steps {
+		result = append(result, step.String())
+	}
+	return result
+}
+
+func cachesWithoutComments(caches []Cache) []Cache {
+	var result []Cache
+	for _, cache := range caches {
+		if !strings.HasPrefix(cache.Name, "#") {
+			result = append(result, cache)
+		}
+	}
+	return result
+}
+
+func cacheStrings(caches []Cache) []string {
+	var result []string
+	for _, cache := range caches {
+		result = append(result, cache.String())
+	}
+	return result
+}
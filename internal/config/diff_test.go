@@ -0,0 +1,149 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parse decodes a claudeway.yaml
+func parse(t *testing.T, data string) *Config {
+	t.Helper()
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("failed to parse %q: %v", data, err)
+	}
+	return &cfg
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want []string
+	}{
+		{
+			name: "same",
+			old:  "init:\n  - make\nbind:\n  - /a:/a\n",
+			new:  "init:\n  - make\nbind:\n  - /a:/a\n",
+		},
+		{
+			name: "comments and command are ignored",
+			old:  "init:\n  - make\n",
+			new:  "init:\n  - '# build'\n  - make\nbind:\n  - '# none yet'\ncommand: bash\n",
+		},
+		{
+			name: "init steps added and removed",
+			old:  "init:\n  - make\n  - make test\n",
+			new:  "init:\n  - make\n  - npm ci\n",
+			want: []string{"init: - make test", "init: + npm ci"},
+		},
+		{
+			name: "init order",
+			old:  "init:\n  - a\n  - b\n",
+			new:  "init:\n  - b\n  - a\n",
+			want: []string{"init: order changed"},
+		},
+		{
+			name: "init step options",
+			old:  "init:\n  - make\n",
+			new:  "init:\n  - run: make\n    retries: 2\n",
+			want: []string{"init: - make", `init: + {"run":"make","retries":2}`},
+		},
+		{
+			name: "init cache",
+			old:  "init:\n  - make\n",
+			new:  "init:\n  cache: image\n  steps:\n    - make\n",
+			want: []string{`init cache: "" -> "image"`},
+		},
+		{
+			name: "bind, copy and caches",
+			old:  "bind:\n  - /a:/a\ncopy:\n  - ~/.gitconfig\ncaches:\n  - npm\n",
+			new:  "bind:\n  - /b:/b\ncaches:\n  - npm\n  - go\n",
+			want: []string{"bind: - /a:/a", "bind: + /b:/b", "copy: - ~/.gitconfig", "caches: + go (~/go/pkg/mod)"},
+		},
+		{
+			name: "workspace",
+			old:  "",
+			new:  "workspace: sync\n",
+			want: []string{"workspace: auto -> sync"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(parse(t, tt.old), parse(t, tt.new))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	base := parse(t, "init:\n  - make\n")
+
+	if base.Hash("sha256:a") != parse(t, "init:\n  - '# build'\n  - make\ncommand: bash\n").Hash("sha256:a") {
+		t.Error("comments and the command changed the hash")
+	}
+	if base.Hash("sha256:a") == base.Hash("sha256:b") {
+		t.Error("the image ID did not change the hash")
+	}
+	if base.Hash("sha256:a") == parse(t, "init:\n  - make test\n").Hash("sha256:a") {
+		t.Error("an init step did not change the hash")
+	}
+	if base.Hash("sha256:a") != parse(t, "init:\n  - make\nworkspace: auto\n").Hash("sha256:a") {
+		t.Error("the default workspace mode changed the hash")
+	}
+}
+
+func TestCanonicalAgent(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	withAgent := func() *Config {
+		cfg := parse(t, "init:\n  - make\nbind:\n  - /a:/a\nagent: claude\n")
+		if err := cfg.applyAgent(); err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+
+	before := withAgent()
+	if before.agentSteps == 0 {
+		t.Fatal("the agent preset added no init steps")
+	}
+	canonical := before.Canonical()
+	if want := InitSteps("make"); !reflect.DeepEqual(canonical.Init, want) {
+		t.Errorf("canonical init = %v, want %v", canonical.Init, want)
+	}
+	if want := []string{"/a:/a"}; !reflect.DeepEqual(canonical.Bind, want) {
+		t.Errorf("canonical binds = %v, want %v", canonical.Bind, want)
+	}
+	if canonical.AgentName() != "claude" {
+		t.Errorf("canonical agent = %q, want claude", canonical.AgentName())
+	}
+
+	// Binds of the agent that exist on the host do not change the hash
+	if err := os.Mkdir(filepath.Join(home, ".claude"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".claude.json"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	after := withAgent()
+	if after.agentBinds != 2 {
+		t.Fatalf("the agent preset bound %d host paths, want 2", after.agentBinds)
+	}
+	if after.Hash("sha256:a") != before.Hash("sha256:a") {
+		t.Error("host paths bound by the agent changed the hash")
+	}
+
+	if got, want := Diff(before, parse(t, "init:\n  - make\nbind:\n  - /a:/a\n")), []string{`agent: "claude" -> ""`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %q, want %q", got, want)
+	}
+}
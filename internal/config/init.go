@@ -0,0 +1,180 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InitCacheImage bakes the leading cacheable init steps into a derived image
+const InitCacheImage = "image"
+
+// Users an init step can run as
+const (
+	InitUserRoot = "root"
+	InitUserHost = "host"
+)
+
+// InitStep is a single initialization command. It is written either as a
+// plain string or as a mapping with the command in run.
+type InitStep struct {
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// Run is a bash script, it may span several lines
+	Run             string         `yaml:"run" json:"run"`
+	Timeout         time.Duration  `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Retries         int            `yaml:"retries,omitempty" json:"retries,omitempty"`
+	ContinueOnError bool           `yaml:"continue_on_error,omitempty" json:"continue_on_error,omitempty"`
+	When            *InitCondition `yaml:"when,omitempty" json:"when,omitempty"`
+	// User is host, the container user mapped to the host user, or root.
+	// Steps run as the host user by default so files they create in the
+	// workspace are owned by the host user.
+	User string `yaml:"user,omitempty" json:"user,omitempty"`
+	// Cache set to false keeps the step out of the init image
+	Cache *bool `yaml:"cache,omitempty" json:"cache,omitempty"`
+}
+
+// InitCondition limits an init step to containers matching all of its fields
+type InitCondition struct {
+	// FileExists is a path in the container, relative to the project
+	// directory or starting with ~/
+	FileExists string `yaml:"file_exists,omitempty" json:"file_exists,omitempty"`
+	// Env is the name of an environment variable that must be non-empty
+	Env string `yaml:"env,omitempty" json:"env,omitempty"`
+	// Arch is the container's architecture, such as amd64 or arm64
+	Arch string `yaml:"arch,omitempty" json:"arch,omitempty"`
+}
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// UnmarshalYAML accepts either a command string or a mapping
+func (s *InitStep) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*s = InitStep{Run: value.Value}
+		return nil
+	}
+
+	type plain InitStep
+	var step plain
+	if err := value.Decode(&step); err != nil {
+		return err
+	}
+	*s = InitStep(step)
+	if err := s.validate(); err != nil {
+		return fmt.Errorf("init step at line %d: %w", value.Line, err)
+	}
+	return nil
+}
+
+// MarshalYAML writes steps with only a command back as a string
+func (s InitStep) MarshalYAML() (interface{}, error) {
+	if s.isPlain() {
+		return s.Run, nil
+	}
+	type plain InitStep
+	return plain(s), nil
+}
+
+// UnmarshalJSON accepts either a command string or an object, configs
+// recorded in container labels before steps had options are plain strings
+func (s *InitStep) UnmarshalJSON(data []byte) error {
+	var run string
+	if err := json.Unmarshal(data, &run); err == nil {
+		*s = InitStep{Run: run}
+		return nil
+	}
+
+	type plain InitStep
+	var step plain
+	if err := json.Unmarshal(data, &step); err != nil {
+		return err
+	}
+	*s = InitStep(step)
+	return nil
+}
+
+// MarshalJSON writes steps with only a command as a string, so the config
+// hash of existing containers stays the same
+func (s InitStep) MarshalJSON() ([]byte, error) {
+	if s.isPlain() {
+		return json.Marshal(s.Run)
+	}
+	type plain InitStep
+	return json.Marshal(plain(s))
+}
+
+func (s InitStep) isPlain() bool {
+	return s == InitStep{Run: s.Run}
+}
+
+// IsComment reports whether the step is a comment entry
+func (s InitStep) IsComment() bool {
+	return s.isPlain() && strings.HasPrefix(s.Run, "#")
+}
+
+// Cacheable reports whether the step can be baked into the init image. Steps
+// that depend on the workspace or the environment only work in the container.
+func (s InitStep) Cacheable() bool {
+	if s.Cache != nil && !*s.Cache {
+		return false
+	}
+	if s.When != nil && (s.When.FileExists != "" || s.When.Env != "") {
+		return false
+	}
+	return true
+}
+
+// RunsAsRoot reports whether the step runs as root instead of the host user
+func (s InitStep) RunsAsRoot() bool {
+	return s.User == InitUserRoot
+}
+
+// Label returns the step's name, or its command if it has none
+func (s InitStep) Label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Run
+}
+
+// String renders the step for diffs
+func (s InitStep) String() string {
+	if s.isPlain() {
+		return s.Run
+	}
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+func (s InitStep) validate() error {
+	if strings.TrimSpace(s.Run) == "" {
+		return fmt.Errorf("run is required")
+	}
+	if s.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if s.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+	switch s.User {
+	case "", InitUserRoot, InitUserHost:
+	default:
+		return fmt.Errorf("user must be %q or %q, got %q", InitUserRoot, InitUserHost, s.User)
+	}
+	if s.When != nil && s.When.Env != "" && !envNamePattern.MatchString(s.When.Env) {
+		return fmt.Errorf("invalid environment variable name %q", s.When.Env)
+	}
+	return nil
+}
+
+// InitSteps converts plain commands to init steps
+func InitSteps(commands ...string) []InitStep {
+	steps := make([]InitStep, len(commands))
+	for i, command := range commands {
+		steps[i] = InitStep{Run: command}
+	}
+	return steps
+}
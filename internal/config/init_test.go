@@ -0,0 +1,129 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestInitStepYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    []InitStep
+		wantErr string
+	}{
+		{
+			name: "plain commands",
+			yaml: "init:\n  - make\n  - '# comment'\n",
+			want: InitSteps("make", "# comment"),
+		},
+		{
+			name: "step with options",
+			yaml: "init:\n  - name: deps\n    run: npm ci\n    timeout: 90s\n    retries: 2\n    continue_on_error: true\n    when:\n      file_exists: package.json\n      arch: amd64\n    user: root\n    cache: false\n",
+			want: []InitStep{{
+				Name:            "deps",
+				Run:             "npm ci",
+				Timeout:         90 * time.Second,
+				Retries:         2,
+				ContinueOnError: true,
+				When:            &InitCondition{FileExists: "package.json", Arch: "amd64"},
+				User:            InitUserRoot,
+				Cache:           new(bool),
+			}},
+		},
+		{
+			name: "section with a default user",
+			yaml: "init:\n  cache: image\n  user: root\n  steps:\n    - apt-get update\n    - run: make\n      user: host\n",
+			want: []InitStep{{Run: "apt-get update", User: InitUserRoot}, {Run: "make", User: InitUserHost}},
+		},
+		{
+			name:    "missing run",
+			yaml:    "init:\n  - name: nothing\n",
+			wantErr: "run is required",
+		},
+		{
+			name:    "negative retries",
+			yaml:    "init:\n  - run: make\n    retries: -1\n",
+			wantErr: "retries must not be negative",
+		},
+		{
+			name:    "unknown user",
+			yaml:    "init:\n  - run: make\n    user: nobody\n",
+			wantErr: `user must be "root" or "host"`,
+		},
+		{
+			name:    "invalid environment variable",
+			yaml:    "init:\n  - run: make\n    when:\n      env: NOT-A-NAME\n",
+			wantErr: "invalid environment variable name",
+		},
+		{
+			name:    "unknown cache mode",
+			yaml:    "init:\n  cache: volume\n  steps:\n    - make\n",
+			wantErr: "init cache must be",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			err := yaml.Unmarshal([]byte(tt.yaml), &cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg.Init, tt.want) {
+				t.Errorf("init = %+v, want %+v", cfg.Init, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitStepJSON(t *testing.T) {
+	// Plain steps stay strings, so configs recorded in container labels
+	// before steps had options still compare equal
+	steps := []InitStep{{Run: "make"}, {Run: "npm ci", Retries: 2}}
+	data, err := json.Marshal(steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `["make",{"run":"npm ci","retries":2}]`; string(data) != want {
+		t.Errorf("json = %s, want %s", data, want)
+	}
+
+	var decoded []InitStep
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, steps) {
+		t.Errorf("decoded = %+v, want %+v", decoded, steps)
+	}
+}
+
+func TestInitStepCacheable(t *testing.T) {
+	no := false
+	tests := []struct {
+		step InitStep
+		want bool
+	}{
+		{InitStep{Run: "make"}, true},
+		{InitStep{Run: "make", When: &InitCondition{Arch: "arm64"}}, true},
+		{InitStep{Run: "make", When: &InitCondition{FileExists: "Makefile"}}, false},
+		{InitStep{Run: "make", When: &InitCondition{Env: "CI"}}, false},
+		{InitStep{Run: "make", Cache: &no}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.step.Cacheable(); got != tt.want {
+			t.Errorf("%s: Cacheable() = %v, want %v", tt.step, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workspace modes
+const (
+	// WorkspaceAuto syncs the workspace with remote daemons and binds it
+	// otherwise
+	WorkspaceAuto = "auto"
+	// WorkspaceBind bind-mounts the project directory
+	WorkspaceBind = "bind"
+	// WorkspaceSync copies the project into a volume and syncs changes in
+	// both directions while sessions run
+	WorkspaceSync = "sync"
+)
+
+// Workspace configures how the project gets into the container
+type Workspace struct {
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+	// Exclude lists names or glob patterns of files and directories that are
+	// not synced, like node_modules
+	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+}
+
+// UnmarshalYAML accepts either a mode or a mapping with mode and exclude
+func (w *Workspace) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		w.Mode = value.Value
+	} else {
+		type plain Workspace
+		if err := value.Decode((*plain)(w)); err != nil {
+			return err
+		}
+	}
+
+	switch w.Mode {
+	case "", WorkspaceAuto, WorkspaceBind, WorkspaceSync:
+		return nil
+	default:
+		return fmt.Errorf("workspace mode must be %q, %q or %q, got %q", WorkspaceAuto, WorkspaceBind, WorkspaceSync, w.Mode)
+	}
+}
+
+// WorkspaceMode returns the configured workspace mode, auto if unset
+func (c *Config) WorkspaceMode() string {
+	if c.Workspace == nil || c.Workspace.Mode == "" {
+		return WorkspaceAuto
+	}
+	return c.Workspace.Mode
+}
+
+// WorkspaceExclude returns the patterns excluded from workspace sync
+func (c *Config) WorkspaceExclude() []string {
+	if c.Workspace == nil {
+		return nil
+	}
+	return withoutComments(c.Workspace.Exclude)
+}
@@ -33,4 +33,33 @@ func GetConfigDir() string {
 		// On Linux and other Unix-like systems, use ~/.config
 		return filepath.Join(homeDir, ".config")
 	}
+}
+
+func GetStateDir() string {
+	// Check XDG_STATE_HOME first
+	if xdgStateHome := os.Getenv("XDG_STATE_HOME"); xdgStateHome != "" {
+		return xdgStateHome
+	}
+
+	// Fallback based on OS
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		// Last resort fallback
+		return "."
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		// On Windows, use %LOCALAPPDATA%
+		if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" {
+			return localAppData
+		}
+		return filepath.Join(homeDir, "AppData", "Local")
+	case "darwin":
+		// On macOS, keep state next to the configuration
+		return filepath.Join(homeDir, "Library", "Application Support")
+	default:
+		// On Linux and other Unix-like systems, use ~/.local/state
+		return filepath.Join(homeDir, ".local", "state")
+	}
 }
\ No newline at end of file
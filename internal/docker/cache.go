@@ -0,0 +1,157 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/common-creation/claudeway/internal/config"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	volumetypes "github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+)
+
+const (
+	// LabelCache holds the name of a shared cache volume
+	LabelCache = "claudeway.cache"
+	// LabelCachePath holds the path a cache volume was first mounted at
+	LabelCachePath = "claudeway.cache-path"
+
+	cacheVolumePrefix = "claudeway-cache-"
+)
+
+// CacheVolume describes a shared cache volume
+type CacheVolume struct {
+	Name      string
+	Volume    string
+	Path      string
+	CreatedAt string
+	// Size and RefCount are only filled in by CacheDiskUsage, -1 if unknown
+	Size     int64
+	RefCount int64
+}
+
+// CacheVolumeName returns the Docker volume name backing a cache
+func CacheVolumeName(name string) string {
+	return cacheVolumePrefix + name
+}
+
+// cacheMounts creates missing cache volumes and returns the mounts for them.
+// The volumes are shared by all projects using the same cache name.
+func (m *Manager) cacheMounts(ctx context.Context, caches []config.Cache) ([]mount.Mount, error) {
+	var mounts []mount.Mount
+	for _, cache := range caches {
+		if strings.HasPrefix(cache.Name, "#") {
+			continue
+		}
+
+		volumeName := CacheVolumeName(cache.Name)
+		if _, err := m.client.VolumeInspect(ctx, volumeName); err != nil {
+			if !client.IsErrNotFound(err) {
+				return nil, fmt.Errorf("failed to inspect cache volume %s: %w", volumeName, err)
+			}
+			_, err := m.client.VolumeCreate(ctx, volumetypes.VolumeCreateBody{
+				Name: volumeName,
+				Labels: map[string]string{
+					LabelCache:     cache.Name,
+					LabelCachePath: cache.Path,
+				},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to create cache volume %s: %w", volumeName, err)
+			}
+		}
+
+		mounts = append(mounts, mount.Mount{
+			Type:   mount.TypeVolume,
+			Source: volumeName,
+			Target: expandContainerPath(cache.Path),
+		})
+	}
+	return mounts, nil
+}
+
+// ListCaches returns all shared cache volumes sorted by name
+func ListCaches(ctx context.Context) ([]CacheVolume, error) {
+	cli, err := NewRuntime()
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	volumes, err := cli.VolumeList(ctx, filters.NewArgs(filters.Arg("label", LabelCache)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	var caches []CacheVolume
+	for _, volume := range volumes.Volumes {
+		caches = append(caches, CacheVolume{
+			Name:      volume.Labels[LabelCache],
+			Volume:    volume.Name,
+			Path:      volume.Labels[LabelCachePath],
+			CreatedAt: volume.CreatedAt,
+			Size:      -1,
+			RefCount:  -1,
+		})
+	}
+
+	sort.Slice(caches, func(i, j int) bool { return caches[i].Name < caches[j].Name })
+	return caches, nil
+}
+
+// CacheDiskUsage returns all shared cache volumes including their size. This
+// can be slow since the daemon walks every volume.
+func CacheDiskUsage(ctx context.Context) ([]CacheVolume, error) {
+	cli, err := NewRuntime()
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	usage, err := cli.DiskUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disk usage: %w", err)
+	}
+
+	var caches []CacheVolume
+	for _, volume := range usage.Volumes {
+		name, ok := volume.Labels[LabelCache]
+		if !ok {
+			continue
+		}
+		cache := CacheVolume{
+			Name:      name,
+			Volume:    volume.Name,
+			Path:      volume.Labels[LabelCachePath],
+			CreatedAt: volume.CreatedAt,
+			Size:      -1,
+			RefCount:  -1,
+		}
+		if volume.UsageData != nil {
+			cache.Size = volume.UsageData.Size
+			cache.RefCount = volume.UsageData.RefCount
+		}
+		caches = append(caches, cache)
+	}
+
+	sort.Slice(caches, func(i, j int) bool { return caches[i].Name < caches[j].Name })
+	return caches, nil
+}
+
+// RemoveCache removes the volume backing a cache. It fails while a container
+// still uses the cache.
+func RemoveCache(ctx context.Context, name string) error {
+	cli, err := NewRuntime()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	if err := cli.VolumeRemove(ctx, CacheVolumeName(name), false); err != nil {
+		return fmt.Errorf("failed to remove cache %s: %w", name, err)
+	}
+	return nil
+}
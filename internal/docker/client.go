@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// newClient creates a Docker client for the endpoint chosen by
+// ResolveEndpoint
+func newClient() (*client.Client, error) {
+	endpoint, err := ResolveEndpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	switch {
+	case strings.HasPrefix(endpoint.Host, "ssh://"):
+		// Tunnel the API through 'docker system dial-stdio' on the remote
+		// machine, like the Docker CLI does
+		dial, err := sshDialer(endpoint.Host)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithHost("http://docker.example.com"), client.WithDialContext(dial))
+	case endpoint.Host != "":
+		opts = append(opts, client.WithHost(endpoint.Host))
+		if endpoint.TLSDir != "" {
+			opts = append(opts, client.WithTLSClientConfig(
+				tlsFile(endpoint.TLSDir, "ca.pem"),
+				tlsFile(endpoint.TLSDir, "cert.pem"),
+				tlsFile(endpoint.TLSDir, "key.pem"),
+			))
+		}
+		if endpoint.SkipTLSVerify {
+			opts = append(opts, withSkipTLSVerify())
+		}
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	return cli, nil
+}
+
+// tlsFile returns the path of a file in a context's TLS directory, "" if the
+// context does not have it
+func tlsFile(dir, name string) string {
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// withSkipTLSVerify turns off verification of the daemon's certificate
+func withSkipTLSVerify() client.Opt {
+	return func(c *client.Client) error {
+		transport, ok := c.HTTPClient().Transport.(*http.Transport)
+		if !ok {
+			return fmt.Errorf("cannot apply tls config to transport: %T", c.HTTPClient().Transport)
+		}
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.InsecureSkipVerify = true
+		return nil
+	}
+}
+
+// podmanHost returns the address of the Podman API to use instead of the
+// default Docker socket, or "" to use the default
+func podmanHost() (string, error) {
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		if !strings.HasPrefix(host, "unix://") && !strings.HasPrefix(host, "tcp://") {
+			return "", fmt.Errorf("CONTAINER_HOST %s is not supported, only unix:// and tcp:// addresses are", host)
+		}
+		return host, nil
+	}
+
+	if _, err := os.Stat("/var/run/docker.sock"); err == nil {
+		return "", nil
+	}
+	for _, socket := range podmanSockets() {
+		if _, err := os.Stat(socket); err == nil {
+			return "unix://" + socket, nil
+		}
+	}
+	return "", nil
+}
+
+// podmanSockets returns where Podman's API socket is, rootless first
+func podmanSockets() []string {
+	var sockets []string
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		sockets = append(sockets, filepath.Join(dir, "podman", "podman.sock"))
+	} else if uid := os.Getuid(); uid > 0 {
+		sockets = append(sockets, fmt.Sprintf("/run/user/%d/podman/podman.sock", uid))
+	}
+	return append(sockets, "/run/podman/podman.sock")
+}
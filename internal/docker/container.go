@@ -2,10 +2,13 @@ package docker
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,28 +16,38 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/mount"
-	"github.com/docker/docker/client"
 	"github.com/common-creation/claudeway/internal/config"
+	"github.com/common-creation/claudeway/internal/guest"
+	"github.com/common-creation/claudeway/internal/term"
 	"github.com/common-creation/claudeway/internal/utils"
 )
 
 const ImageName = "claudeway:latest"
 
 type Manager struct {
-	client       *client.Client
+	client       Runtime
 	containerName string
 	workDir      string
+	ephemeral    bool
+	output       io.Writer
+	engine       *Engine
 }
 
 func NewManager() (*Manager, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	workDir, err := os.Getwd()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create docker client: %w", err)
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
 	}
 
-	workDir, err := os.Getwd()
+	return NewManagerForDir(workDir)
+}
+
+// NewManagerForDir returns a manager for the container of the project in
+// workDir instead of the current directory
+func NewManagerForDir(workDir string) (*Manager, error) {
+	cli, err := NewRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get working directory: %w", err)
+		return nil, err
 	}
 
 	containerName := fmt.Sprintf("claudeway-%s", utils.HashPath(workDir))
@@ -46,10 +59,55 @@ func NewManager() (*Manager, error) {
 	}, nil
 }
 
+// Ephemeral returns a manager for a new, uniquely named throwaway container
+// of the same project
+func (m *Manager) Ephemeral() (*Manager, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("failed to generate container name: %w", err)
+	}
+
+	return &Manager{
+		client:        m.client,
+		containerName: fmt.Sprintf("%s-run-%s", m.containerName, hex.EncodeToString(suffix)),
+		workDir:       m.workDir,
+		ephemeral:     true,
+		output:        m.output,
+		engine:        m.engine,
+	}, nil
+}
+
+// SetOutput sets where initialization output and warnings are written,
+// os.Stdout and os.Stderr by default
+func (m *Manager) SetOutput(w io.Writer) {
+	m.output = w
+}
+
+func (m *Manager) out() io.Writer {
+	if m.output == nil {
+		return os.Stdout
+	}
+	return m.output
+}
+
+// errOut is where warnings are written
+func (m *Manager) errOut() io.Writer {
+	if m.output == nil {
+		return os.Stderr
+	}
+	return m.output
+}
+
+// nameFilter matches exactly this manager's container, the plain name filter
+// also matches other containers containing the name
+func (m *Manager) nameFilter() filters.Args {
+	return filters.NewArgs(filters.Arg("name", "^/"+m.containerName+"$"))
+}
+
 func (m *Manager) ContainerExists(ctx context.Context) (bool, error) {
 	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{
 		All:     true,
-		Filters: filters.NewArgs(filters.Arg("name", m.containerName)),
+		Filters: m.nameFilter(),
 	})
 	if err != nil {
 		return false, err
@@ -60,7 +118,7 @@ func (m *Manager) ContainerExists(ctx context.Context) (bool, error) {
 
 func (m *Manager) IsContainerRunning(ctx context.Context) (bool, error) {
 	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{
-		Filters: filters.NewArgs(filters.Arg("name", m.containerName)),
+		Filters: m.nameFilter(),
 	})
 	if err != nil {
 		return false, err
@@ -70,20 +128,37 @@ func (m *Manager) IsContainerRunning(ctx context.Context) (bool, error) {
 }
 
 func (m *Manager) CreateAndStartContainer(ctx context.Context, cfg *config.Config) error {
-	// Prepare mounts
-	mounts := []mount.Mount{
-		{
-			Type:   mount.TypeBind,
-			Source: m.workDir,
-			Target: m.workDir,
-		},
+	// A remote daemon cannot see this machine's files, the workspace is
+	// synced into a volume instead of bind-mounting it
+	syncWorkspace, err := syncsWorkspace(cfg)
+	if err != nil {
+		return err
+	}
+	workspaceMount := mount.Mount{
+		Type:   mount.TypeBind,
+		Source: m.workDir,
+		Target: m.workDir,
+	}
+	freshWorkspace := false
+	if syncWorkspace {
+		workspaceMount, freshWorkspace, err = m.workspaceMount(ctx)
+		if err != nil {
+			return err
+		}
 	}
 
+	// Prepare mounts
+	mounts := []mount.Mount{workspaceMount}
+
 	// Add additional bind mounts
 	for _, bind := range cfg.Bind {
 		if strings.HasPrefix(bind, "#") {
 			continue
 		}
+		if syncWorkspace {
+			fmt.Fprintf(m.out(), "Warning: skipping bind %s, the daemon cannot see this machine's files\n", bind)
+			continue
+		}
 		
 		// Parse source and target from bind string (format: "source:target" or just "path")
 		var sourcePath, targetPath string
@@ -119,17 +194,7 @@ func (m *Manager) CreateAndStartContainer(ctx context.Context, cfg *config.Confi
 		}
 		
 		// For target path, expand ~ to container's home directory
-		if strings.HasPrefix(targetPath, "~/") {
-			// Check if we have host user info
-			hostUser := os.Getenv("USER")
-			if hostUser != "" && os.Getuid() >= 0 {
-				// Use host user's home directory
-				targetPath = filepath.Join("/home", hostUser, targetPath[2:])
-			} else {
-				// Fallback to root
-				targetPath = filepath.Join("/root", targetPath[2:])
-			}
-		}
+		targetPath = expandContainerPath(targetPath)
 		
 		// Get absolute path for target
 		absTargetPath, err := filepath.Abs(targetPath)
@@ -144,7 +209,10 @@ func (m *Manager) CreateAndStartContainer(ctx context.Context, cfg *config.Confi
 		})
 	}
 
-	// Add copy mounts as read-only under /host
+	// Add copy mounts as read-only under /host, the guest agent copies them
+	// into place when the container starts
+	spec := &guest.Spec{}
+	copySources := map[string]string{}
 	for _, copy := range cfg.Copy {
 		if strings.HasPrefix(copy, "#") {
 			continue
@@ -159,19 +227,38 @@ func (m *Manager) CreateAndStartContainer(ctx context.Context, cfg *config.Confi
 			expandedPath = filepath.Join(home, copy[2:])
 		}
 
-		// Get absolute path
-		absPath, err := filepath.Abs(expandedPath)
-		if err != nil {
-			return fmt.Errorf("failed to get absolute path for %s: %w", copy, err)
+		// Relative paths are relative to the project, which is also the
+		// container's working directory
+		absPath := expandedPath
+		if !filepath.IsAbs(absPath) {
+			absPath = filepath.Join(m.workDir, absPath)
+		}
+		dest := expandContainerPath(copy)
+		if !filepath.IsAbs(dest) {
+			dest = filepath.Join(m.workDir, dest)
 		}
 
-		mounts = append(mounts, mount.Mount{
-			Type:     mount.TypeBind,
-			Source:   absPath,
-			Target:   filepath.Join("/host", absPath),
-			ReadOnly: true,
-		})
+		source := filepath.Join("/host", absPath)
+		if syncWorkspace {
+			// Uploaded once the container is created
+			copySources[absPath] = source
+		} else {
+			mounts = append(mounts, mount.Mount{
+				Type:     mount.TypeBind,
+				Source:   absPath,
+				Target:   source,
+				ReadOnly: true,
+			})
+		}
+		spec.Copy = append(spec.Copy, guest.CopyEntry{Source: source, Dest: dest})
+	}
+
+	// Add shared cache volumes
+	cacheMounts, err := m.cacheMounts(ctx, cfg.Caches)
+	if err != nil {
+		return err
 	}
+	mounts = append(mounts, cacheMounts...)
 
 	// Get environment variables - only copy essential ones
 	env := []string{
@@ -203,18 +290,32 @@ func (m *Manager) CreateAndStartContainer(ctx context.Context, cfg *config.Confi
 		env = append(env, "HOME=/root")
 	}
 
-	// Prepare init commands
-	initCommands := []string{}
-	for _, cmd := range cfg.Init {
-		if !strings.HasPrefix(cmd, "#") {
-			initCommands = append(initCommands, cmd)
+	// Label the container so configuration drift can be detected later
+	labels, err := m.containerLabels(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	labels[LabelWorkspace] = config.WorkspaceBind
+	if syncWorkspace {
+		labels[LabelWorkspace] = config.WorkspaceSync
+	}
+
+	// Cacheable init steps are baked into a derived image if enabled
+	image := ImageName
+	cached, remaining := splitCachedInit(cfg)
+	if len(cached) > 0 {
+		image, err = m.initImage(ctx, cached)
+		if err != nil {
+			return err
 		}
+		fmt.Fprintf(m.out(), "Using init image %s with %d cached steps\n", image, len(cached))
 	}
 
 	// Create container config
 	containerConfig := &container.Config{
-		Image:        ImageName,
+		Image:        image,
 		Env:          env,
+		Labels:       labels,
 		WorkingDir:   m.workDir,
 		Tty:          true,
 		AttachStdin:  true,
@@ -224,31 +325,51 @@ func (m *Manager) CreateAndStartContainer(ctx context.Context, cfg *config.Confi
 		StdinOnce:    false,
 	}
 
-	// Add init commands and copy files as environment variables
-	if len(initCommands) > 0 {
-		containerConfig.Env = append(containerConfig.Env, fmt.Sprintf("CLAUDEWAY_INIT=%s", strings.Join(initCommands, ";")))
-	}
-
-	copyFiles := []string{}
-	for _, copy := range cfg.Copy {
-		if !strings.HasPrefix(copy, "#") {
-			copyFiles = append(copyFiles, copy)
-		}
-	}
-	if len(copyFiles) > 0 {
-		containerConfig.Env = append(containerConfig.Env, fmt.Sprintf("CLAUDEWAY_COPY=%s", strings.Join(copyFiles, ";")))
+	spec.User = containerUser()
+	for _, cacheMount := range cacheMounts {
+		spec.Caches = append(spec.Caches, cacheMount.Target)
 	}
+	spec.Init = guestSteps(remaining)
 
 	hostConfig := &container.HostConfig{
 		Mounts: mounts,
 	}
 
+	// Rootless Podman maps the host user into the container and adds its
+	// account, the guest agent still runs as root to set it up
+	engine, err := m.Engine(ctx)
+	if err != nil {
+		return err
+	}
+	if engine.KeepsUserID() {
+		hostConfig.UsernsMode = "keep-id"
+		containerConfig.User = "root"
+		if spec.User != nil {
+			spec.User.Existing = true
+			spec.User.Groups = nil
+		}
+	}
+
 	// Create container
 	resp, err := m.client.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, m.containerName)
 	if err != nil {
 		return fmt.Errorf("failed to create container: %w", err)
 	}
 
+	if syncWorkspace {
+		if err := m.seedWorkspace(ctx, resp.ID, cfg.WorkspaceExclude(), freshWorkspace); err != nil {
+			return err
+		}
+		if err := m.uploadCopies(ctx, resp.ID, copySources); err != nil {
+			return err
+		}
+	}
+
+	// The guest agent reads the spec when the container starts
+	if err := m.writeGuestSpec(ctx, resp.ID, spec); err != nil {
+		return err
+	}
+
 	// Start container
 	if err := m.client.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
 		return fmt.Errorf("failed to start container: %w", err)
@@ -257,7 +378,49 @@ func (m *Manager) CreateAndStartContainer(ctx context.Context, cfg *config.Confi
 	return nil
 }
 
+// expandContainerPath expands a leading ~/ to the home directory of the
+// container user
+func expandContainerPath(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	// Check if we have host user info
+	hostUser := os.Getenv("USER")
+	if hostUser != "" && os.Getuid() >= 0 {
+		// Use host user's home directory
+		return filepath.Join("/home", hostUser, path[2:])
+	}
+	// Fallback to root
+	return filepath.Join("/root", path[2:])
+}
+
+// StartContainer starts an existing, stopped container
+func (m *Manager) StartContainer(ctx context.Context) error {
+	if err := m.client.ContainerStart(ctx, m.containerName, types.ContainerStartOptions{}); err != nil {
+		return fmt.Errorf("failed to start container: %w", err)
+	}
+	return nil
+}
+
+// StopContainer stops the container but keeps it, so it can be started again
+// without repeating the initialization
+func (m *Manager) StopContainer(ctx context.Context) error {
+	if _, err := m.syncWorkspaceBeforeStop(ctx); err != nil {
+		fmt.Fprintf(m.errOut(), "Warning: failed to sync the workspace, changes are kept in volume %s: %v\n", m.workspaceVolume(), err)
+	}
+	if err := m.client.ContainerStop(ctx, m.containerName, nil); err != nil {
+		return fmt.Errorf("failed to stop container: %w", err)
+	}
+	return nil
+}
+
 func (m *Manager) StopAndRemoveContainer(ctx context.Context) error {
+	synced, syncErr := m.syncWorkspaceBeforeStop(ctx)
+	if syncErr != nil {
+		fmt.Fprintf(m.errOut(), "Warning: failed to sync the workspace, changes are kept in volume %s: %v\n", m.workspaceVolume(), syncErr)
+	}
+
 	// Stop container
 	if err := m.client.ContainerStop(ctx, m.containerName, nil); err != nil {
 		return fmt.Errorf("failed to stop container: %w", err)
@@ -268,215 +431,263 @@ func (m *Manager) StopAndRemoveContainer(ctx context.Context) error {
 		return fmt.Errorf("failed to remove container: %w", err)
 	}
 
+	// The workspace volume of the project's container is reused by the next
+	// one, a throwaway container's is not
+	if synced && syncErr == nil && m.ephemeral {
+		if err := m.client.VolumeRemove(ctx, m.workspaceVolume(), false); err != nil {
+			return fmt.Errorf("failed to remove workspace volume: %w", err)
+		}
+		os.Remove(m.syncStateFile())
+	}
+
 	return nil
 }
 
-func (m *Manager) ExecInteractive(ctx context.Context, cmd []string) error {
-	if len(cmd) == 0 {
-		cmd = []string{"/bin/bash", "-l"}
-	}
-
-	// First, ensure user is set up by running the setup_user function
-	setupScript := `
-if [ -n "$HOST_UID" ] && [ -n "$HOST_GID" ] && [ -n "$HOST_USER" ]; then
-    if ! id -u "$HOST_USER" >/dev/null 2>&1; then
-        echo "Creating user $HOST_USER with UID=$HOST_UID GID=$HOST_GID..."
-        if ! getent group "$HOST_GID" >/dev/null 2>&1; then
-            groupadd -g "$HOST_GID" "$HOST_USER" 2>/dev/null || true
-        fi
-        useradd -u "$HOST_UID" -g "$HOST_GID" -m -d "/home/$HOST_USER" -s /bin/bash "$HOST_USER" 2>/dev/null || true
-        echo '. /opt/asdf/asdf.sh' >> "/home/$HOST_USER/.bashrc"
-        echo '. /opt/asdf/completions/asdf.bash' >> "/home/$HOST_USER/.bashrc"
-        if [ -f "/root/.tool-versions" ]; then
-            cp "/root/.tool-versions" "/home/$HOST_USER/.tool-versions"
-            chown "$HOST_USER:$HOST_GID" "/home/$HOST_USER/.tool-versions"
-        fi
-        echo "$HOST_USER ALL=(ALL) NOPASSWD:ALL" > /etc/sudoers.d/$HOST_USER
-        chmod 0440 /etc/sudoers.d/$HOST_USER
-    fi
-fi
-`
-	setupConfig := types.ExecConfig{
-		Cmd:          []string{"/bin/bash", "-c", setupScript},
-		AttachStdout: true,
-		AttachStderr: true,
-		Env: []string{
-			fmt.Sprintf("HOST_UID=%d", os.Getuid()),
-			fmt.Sprintf("HOST_GID=%d", os.Getgid()),
-			fmt.Sprintf("HOST_USER=%s", os.Getenv("USER")),
-		},
-	}
-	
-	setupResp, err := m.client.ContainerExecCreate(ctx, m.containerName, setupConfig)
-	if err == nil {
-		m.client.ContainerExecStart(ctx, setupResp.ID, types.ExecStartCheck{})
-		// Wait a moment for user setup to complete
-		time.Sleep(500 * time.Millisecond)
+// ExecInteractive runs a command like ExecAsUser, attached to the standard
+// streams. Without a command it opens a login shell.
+func (m *Manager) ExecInteractive(ctx context.Context, options ExecOptions) error {
+	if len(options.Cmd) == 0 {
+		options.Cmd = []string{"/bin/bash", "-l"}
 	}
 
-	// Check if we have host user info
-	hostUser := os.Getenv("USER")
-	hostUID := os.Getuid()
-	
-	// If we have host user info, use sudo to switch user
-	if hostUser != "" && hostUID >= 0 {
-		// Prepend sudo command to run as the host user
-		sudoCmd := []string{"sudo", "-u", hostUser, "-E", "-H"}
-		cmd = append(sudoCmd, cmd...)
-	}
+	// Only allocate a TTY when both ends are terminals, so the command can be
+	// used in pipes and scripts
+	options.Tty = term.IsTerminal(os.Stdin.Fd()) && term.IsTerminal(os.Stdout.Fd())
+	options.Stdin = os.Stdin
+	options.Stdout = os.Stdout
+	options.Stderr = os.Stderr
 
-	execConfig := types.ExecConfig{
-		Cmd:          cmd,
-		AttachStdin:  true,
-		AttachStdout: true,
-		AttachStderr: true,
-		Tty:          true,
-		Env: []string{
-			fmt.Sprintf("HOST_UID=%d", os.Getuid()),
-			fmt.Sprintf("HOST_GID=%d", os.Getgid()),
-			fmt.Sprintf("HOST_USER=%s", os.Getenv("USER")),
-		},
+	// A synced workspace is kept in sync while the session runs
+	stopSync, err := m.startWorkspaceSync(ctx)
+	if err != nil {
+		return err
 	}
-
-	execResp, err := m.client.ContainerExecCreate(ctx, m.containerName, execConfig)
+	exitCode, err := m.ExecAsUser(ctx, options)
+	stopSync()
 	if err != nil {
-		return fmt.Errorf("failed to create exec: %w", err)
+		return err
+	}
+	if exitCode != 0 {
+		return &ExitError{Code: exitCode}
 	}
+	return nil
+}
 
-	resp, err := m.client.ContainerExecAttach(ctx, execResp.ID, types.ExecStartCheck{
-		Tty: true,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to attach to exec: %w", err)
+// ExecAsUser runs a command like Exec, but as the host user instead of root
+// unless options.User is set. It runs in the project directory unless
+// options.WorkingDir is set.
+func (m *Manager) ExecAsUser(ctx context.Context, options ExecOptions) (int, error) {
+	if options.WorkingDir == "" {
+		options.WorkingDir = m.workDir
+	}
+	if options.User != "" {
+		return m.Exec(ctx, options)
 	}
-	defer resp.Close()
 
-	// Set terminal to raw mode
-	oldState, err := SetRawTerminal(os.Stdin.Fd())
-	if err != nil {
-		return fmt.Errorf("failed to set raw terminal: %w", err)
+	// The guest agent creates the user when the container starts
+	if err := m.waitForUser(ctx); err != nil {
+		return -1, err
 	}
-	defer RestoreTerminal(os.Stdin.Fd(), oldState)
 
-	// Handle resize
-	resizeTty := func() {
-		size, err := GetTerminalSize()
-		if err == nil {
-			m.client.ContainerExecResize(ctx, execResp.ID, types.ResizeOptions{
-				Height: uint(size.Height),
-				Width:  uint(size.Width),
-			})
+	if user := containerUser(); user != nil {
+		// The guest agent may have picked another name to avoid a collision
+		identity, err := m.Identity(ctx)
+		if err != nil {
+			return -1, err
+		}
+		if identity != nil {
+			user.Name = identity.Name
 		}
-	}
-	resizeTty()
 
-	// Start goroutines for input/output
-	errCh := make(chan error, 2)
+		// Without an explicit group the runtime looks the user up in the
+		// container and adds its supplementary groups, such as the group
+		// owning docker.sock
+		options.User = strconv.Itoa(user.UID)
+		if identity != nil && identity.Name != "" {
+			options.User = identity.Name
+		}
+		// Set the identity first so options.Env can still override it
+		options.Env = append([]string{
+			"HOME=" + user.Home,
+			"USER=" + user.Name,
+			"LOGNAME=" + user.Name,
+		}, options.Env...)
+	}
+	return m.Exec(ctx, options)
+}
 
-	go func() {
-		_, err := io.Copy(resp.Conn, os.Stdin)
-		errCh <- err
-	}()
+// waitForUser waits until the guest agent created the container user, which
+// it does before copying files and running the init steps
+func (m *Manager) waitForUser(ctx context.Context) error {
+	if containerUser() == nil {
+		return nil
+	}
 
-	go func() {
-		_, err := io.Copy(os.Stdout, resp.Reader)
-		errCh <- err
-	}()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
 
-	// Wait for either goroutine to finish
-	err = <-errCh
-	return err
+	start := time.Now()
+	for {
+		progress, err := m.InitProgress(ctx)
+		if err != nil {
+			return err
+		}
+		switch {
+		case progress.UserReady || progress.Complete:
+			return nil
+		case progress.Failed:
+			return fmt.Errorf("container initialization failed (see 'claudeway logs --init')")
+		case !progress.Started && time.Since(start) > time.Minute:
+			return fmt.Errorf("no initialization status from the container, recreate it with --recreate")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
 func (m *Manager) GetContainerName() string {
 	return m.containerName
 }
 
-// WaitForInitialization waits for container initialization to complete
+// GetWorkDir returns the project directory of the container
+func (m *Manager) GetWorkDir() string {
+	return m.workDir
+}
+
+// WaitForInitialization waits for container initialization to complete and
+// shows the progress of the init steps. The raw init output is persisted to
+// a log file instead so it can be inspected later with 'claudeway logs --init'.
 func (m *Manager) WaitForInitialization(ctx context.Context) error {
-	fmt.Println("Container logs:")
-	
-	// Start following logs immediately
-	options := types.ContainerLogsOptions{
+	initLog, err := m.createInitLog()
+	if err != nil {
+		fmt.Fprintf(m.errOut(), "Warning: failed to create init log: %v\n", err)
+		return m.waitForInitialization(ctx, m.out(), io.Discard)
+	}
+	defer initLog.Close()
+
+	err = m.waitForInitialization(ctx, m.out(), initLog)
+	if err != nil {
+		fmt.Fprintf(initLog, "\n[claudeway] %v\n", err)
+	}
+	return err
+}
+
+func (m *Manager) waitForInitialization(ctx context.Context, out io.Writer, rawLog io.Writer) error {
+	// Only look at the current run, a restarted container still has the
+	// logs and status of its previous runs
+	inspect, err := m.client.ContainerInspect(ctx, m.containerName)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container: %w", err)
+	}
+	startedAt, _ := time.Parse(time.RFC3339Nano, inspect.State.StartedAt)
+
+	reader, err := m.client.ContainerLogs(ctx, m.containerName, types.ContainerLogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
 		Follow:     true,
-		Timestamps: false,
-	}
-
-	reader, err := m.client.ContainerLogs(ctx, m.containerName, options)
+		Since:      inspect.State.StartedAt,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to get container logs: %w", err)
 	}
-	defer reader.Close()
-	
-	// Channel to signal completion
-	done := make(chan error, 1)
-	
-	// Start log processing goroutine
+	logsDone := make(chan struct{})
 	go func() {
-		// Simple byte-by-byte reading with line accumulation
-		buffer := make([]byte, 4096)
-		var accumulated strings.Builder
-		
-		for {
-			n, err := reader.Read(buffer)
-			if n > 0 {
-				// Print to stdout
-				os.Stdout.Write(buffer[:n])
-				
-				// Accumulate for pattern matching
-				accumulated.WriteString(string(buffer[:n]))
-				content := accumulated.String()
-				
-				// Check for completion
-				if strings.Contains(content, "Claudeway initialization complete.") {
-					done <- nil
-					return
-				}
-				
-				// Check for failure
-				if strings.Contains(content, "Claudeway initialization failed.") {
-					done <- fmt.Errorf("initialization failed: one or more init commands failed")
-					return
-				}
-			}
-			
-			if err != nil {
-				// Any error (including EOF) means log stream ended
-				done <- fmt.Errorf("log stream ended unexpectedly")
-				return
-			}
+		defer close(logsDone)
+		io.Copy(rawLog, reader)
+	}()
+	// Give the log stream a moment to catch up before closing it
+	defer func() {
+		select {
+		case <-logsDone:
+		case <-time.After(500 * time.Millisecond):
 		}
+		reader.Close()
+		<-logsDone
 	}()
-	
-	// Monitor container status
-	ticker := time.NewTicker(200 * time.Millisecond)
+
+	fmt.Fprintln(out, "Initializing container...")
+	renderer := newInitRenderer(out)
+
+	ticker := time.NewTicker(250 * time.Millisecond)
 	defer ticker.Stop()
-	
+
 	for {
-		select {
-		case err := <-done:
+		// Check the state before reading the progress, so a container that
+		// stopped has written all of its events
+		inspect, err := m.client.ContainerInspect(ctx, m.containerName)
+		if err != nil {
+			return fmt.Errorf("failed to inspect container: %w", err)
+		}
+
+		progress, err := m.initProgress(ctx, startedAt)
+		if err != nil {
 			return err
-			
-		case <-ticker.C:
-			// Check if container is still running
-			inspect, err := m.client.ContainerInspect(ctx, m.containerName)
-			if err != nil {
-				return fmt.Errorf("failed to inspect container: %w", err)
-			}
-			
-			if !inspect.State.Running {
-				// Container stopped - this means initialization failed
-				return fmt.Errorf("container stopped unexpectedly (initialization failed)")
+		}
+		renderer.render(progress)
+
+		switch {
+		case progress.Complete:
+			if progress.Skipped {
+				fmt.Fprintln(out, "  Initialization already done for this configuration, skipped")
 			}
-			
+			m.reportIdentity(ctx, out)
+			return nil
+		case progress.Failed:
+			return m.initFailure(ctx, out, progress)
+		case !inspect.State.Running:
+			return fmt.Errorf("container stopped unexpectedly with exit code %d (see 'claudeway logs --init')", inspect.State.ExitCode)
+		case !progress.Started && time.Since(startedAt) > time.Minute:
+			// Containers created from an image before the progress protocol
+			// never write a status
+			return fmt.Errorf("no initialization status from the container, recreate it with --recreate")
+		}
+
+		select {
+		case <-ticker.C:
 		case <-ctx.Done():
 			return ctx.Err()
 		}
 	}
 }
 
+// reportIdentity shows how the host user was mapped when the image's
+// accounts had to be changed for it
+func (m *Manager) reportIdentity(ctx context.Context, out io.Writer) {
+	identity, err := m.Identity(ctx)
+	if err != nil || identity == nil || len(identity.Changes) == 0 {
+		return
+	}
+
+	fmt.Fprintf(out, "  Host user mapped to %s (%d:%d):\n", identity.Name, identity.UID, identity.GID)
+	for _, change := range identity.Changes {
+		fmt.Fprintf(out, "    %s\n", change)
+	}
+}
+
+// initFailure shows the last lines of the failed step and returns an error
+// describing it
+func (m *Manager) initFailure(ctx context.Context, out io.Writer, progress *InitProgress) error {
+	for _, step := range progress.Steps {
+		if step.Number != progress.FailedStep {
+			continue
+		}
+
+		if lines, err := m.initStepOutput(ctx, step.Number, initFailedLines); err == nil {
+			fmt.Fprintf(out, "\nLast lines of step %d:\n", step.Number)
+			for _, line := range lines {
+				fmt.Fprintf(out, "  | %s\n", line)
+			}
+			fmt.Fprintln(out)
+		}
+		return fmt.Errorf("init step %d/%d failed with exit code %d: %s", step.Number, progress.Total, step.ExitCode, stepLabel(step.Name))
+	}
+	return fmt.Errorf("one or more init steps failed")
+}
+
 // waitForInitializationFile uses file-based approach to check for initialization completion
 func (m *Manager) waitForInitializationFile(ctx context.Context, timeout time.Duration) error {
 	start := time.Now()
@@ -489,41 +700,13 @@ func (m *Manager) waitForInitializationFile(ctx context.Context, timeout time.Du
 			return ctx.Err()
 		case <-ticker.C:
 			// Check if initialization is complete by looking for the marker file
-			execConfig := types.ExecConfig{
-				Cmd:          []string{"test", "-f", "/tmp/.claudeway_init_complete"},
-				AttachStdout: false,
-				AttachStderr: false,
-			}
-
-			execResp, err := m.client.ContainerExecCreate(ctx, m.containerName, execConfig)
-			if err != nil {
-				return fmt.Errorf("failed to create exec for init check: %w", err)
-			}
-
-			inspectResp, err := m.client.ContainerExecInspect(ctx, execResp.ID)
+			exitCode, err := m.execExitCode(ctx, []string{"test", "-f", guest.InitMarker})
 			if err != nil {
-				return fmt.Errorf("failed to inspect exec: %w", err)
-			}
-
-			// Start the exec
-			if err := m.client.ContainerExecStart(ctx, execResp.ID, types.ExecStartCheck{}); err != nil {
-				return fmt.Errorf("failed to start exec: %w", err)
-			}
-
-			// Wait for the exec to complete
-			for {
-				inspectResp, err = m.client.ContainerExecInspect(ctx, execResp.ID)
-				if err != nil {
-					return fmt.Errorf("failed to inspect exec: %w", err)
-				}
-				if !inspectResp.Running {
-					break
-				}
-				time.Sleep(50 * time.Millisecond)
+				return fmt.Errorf("failed to check init marker: %w", err)
 			}
 
 			// Exit code 0 means the file exists
-			if inspectResp.ExitCode == 0 {
+			if exitCode == 0 {
 				return nil
 			}
 
@@ -534,3 +717,21 @@ func (m *Manager) waitForInitializationFile(ctx context.Context, timeout time.Du
 		}
 	}
 }
+
+// execExitCode runs a command in the container without attaching to it and
+// returns its exit code
+func (m *Manager) execExitCode(ctx context.Context, cmd []string) (int, error) {
+	execResp, err := m.client.ContainerExecCreate(ctx, m.containerName, types.ExecConfig{
+		Cmd: cmd,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	if err := m.client.ContainerExecStart(ctx, execResp.ID, types.ExecStartCheck{}); err != nil {
+		return 0, fmt.Errorf("failed to start exec: %w", err)
+	}
+
+	// Wait for the exec to complete
+	return m.waitExec(ctx, execResp.ID)
+}
@@ -0,0 +1,142 @@
+package docker_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/common-creation/claudeway/internal/config"
+	"github.com/common-creation/claudeway/internal/docker"
+	"github.com/common-creation/claudeway/internal/docker/fake"
+	"github.com/common-creation/claudeway/internal/guest"
+	"github.com/docker/docker/api/types"
+)
+
+// newManager returns a manager for a fresh project with the claudeway
+// image built, against the fake runtime
+func newManager(t *testing.T) (*fake.Runtime, *docker.Manager) {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, ".local", "state"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "")
+
+	runtime := fake.New()
+	t.Cleanup(runtime.Install())
+
+	if _, err := docker.BuildImageWithOptions(context.Background(), docker.BuildOptions{Output: io.Discard}); err != nil {
+		t.Fatalf("failed to build the image: %v", err)
+	}
+	manager, err := docker.NewManagerForDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.SetOutput(io.Discard)
+	return runtime, manager
+}
+
+func TestContainerLifecycle(t *testing.T) {
+	runtime, manager := newManager(t)
+	runtime.ExecHandler = func(container string, config types.ExecConfig, stdin io.Reader, stdout, stderr io.Writer) int {
+		input, _ := io.ReadAll(stdin)
+		fmt.Fprintf(stdout, "%s in %s: %s", strings.Join(fake.Command(config), " "), config.WorkingDir, input)
+		return 0
+	}
+	ctx := context.Background()
+	cfg := &config.Config{Init: config.InitSteps("echo ready")}
+
+	if err := manager.CreateAndStartContainer(ctx, cfg); err != nil {
+		t.Fatalf("failed to start the container: %v", err)
+	}
+	if err := manager.WaitForInitialization(ctx); err != nil {
+		t.Fatalf("initialization failed: %v", err)
+	}
+	running, err := manager.IsContainerRunning(ctx)
+	if err != nil || !running {
+		t.Fatalf("container is not running after initialization: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	exitCode, err := manager.ExecAsUser(ctx, docker.ExecOptions{
+		Cmd:    []string{"cat"},
+		Stdin:  strings.NewReader("hello"),
+		Stdout: &stdout,
+	})
+	if err != nil || exitCode != 0 {
+		t.Fatalf("exec failed with exit code %d: %v", exitCode, err)
+	}
+	if want := "cat in " + manager.GetWorkDir() + ": hello"; stdout.String() != want {
+		t.Fatalf("exec output = %q, want %q", stdout.String(), want)
+	}
+
+	if err := manager.StopAndRemoveContainer(ctx); err != nil {
+		t.Fatalf("failed to remove the container: %v", err)
+	}
+	exists, err := manager.ContainerExists(ctx)
+	if err != nil || exists {
+		t.Fatalf("container still exists after removal: %v", err)
+	}
+}
+
+func TestContainerExitCode(t *testing.T) {
+	runtime, manager := newManager(t)
+	runtime.ExecHandler = func(container string, config types.ExecConfig, stdin io.Reader, stdout, stderr io.Writer) int {
+		fmt.Fprint(stderr, "failed")
+		return 2
+	}
+	ctx := context.Background()
+
+	if err := manager.CreateAndStartContainer(ctx, &config.Config{}); err != nil {
+		t.Fatalf("failed to start the container: %v", err)
+	}
+	if err := manager.WaitForInitialization(ctx); err != nil {
+		t.Fatalf("initialization failed: %v", err)
+	}
+
+	var stderr bytes.Buffer
+	exitCode, err := manager.ExecAsUser(ctx, docker.ExecOptions{Cmd: []string{"false"}, Stderr: &stderr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exitCode != 2 || stderr.String() != "failed" {
+		t.Fatalf("exit code %d with %q, want 2 with %q", exitCode, stderr.String(), "failed")
+	}
+}
+
+func TestContainerInitFailure(t *testing.T) {
+	runtime, manager := newManager(t)
+	var ran []string
+	runtime.StepResult = func(step guest.Step) int {
+		ran = append(ran, step.Name)
+		if step.Name == "exit 1" {
+			return 1
+		}
+		return 0
+	}
+	ctx := context.Background()
+	cfg := &config.Config{Init: config.InitSteps("echo ready", "exit 1", "echo never")}
+
+	if err := manager.CreateAndStartContainer(ctx, cfg); err != nil {
+		t.Fatalf("failed to start the container: %v", err)
+	}
+	err := manager.WaitForInitialization(ctx)
+	if err == nil || !strings.Contains(err.Error(), "init step 2/3") {
+		t.Fatalf("expected init step 2/3 to fail, got %v", err)
+	}
+	if want := []string{"echo ready", "exit 1"}; !reflect.DeepEqual(ran, want) {
+		t.Fatalf("ran steps %q, want %q", ran, want)
+	}
+	running, err := manager.IsContainerRunning(ctx)
+	if err != nil || running {
+		t.Fatalf("container is still running after a failed init step: %v", err)
+	}
+}
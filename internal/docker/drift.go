@@ -0,0 +1,140 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/docker/docker/client"
+	"github.com/common-creation/claudeway/internal/config"
+)
+
+const (
+	// LabelManaged marks images and volumes created by claudeway
+	LabelManaged = "claudeway.managed"
+	// LabelProject holds the absolute path of the project a container belongs to
+	LabelProject = "claudeway.project"
+	// LabelConfig holds the canonical JSON of the config a container was created with
+	LabelConfig = "claudeway.config"
+	// LabelEphemeral marks throwaway containers created by 'claudeway run'
+	LabelEphemeral = "claudeway.ephemeral"
+	// LabelConfigHash holds the hash of the config and image ID a container was created with
+	LabelConfigHash = "claudeway.config-hash"
+	// LabelAgent holds the agent preset a container was created with
+	LabelAgent = "claudeway.agent"
+	// LabelAgentSource holds where the agent preset was chosen, cli or config
+	LabelAgentSource = "claudeway.agent-source"
+)
+
+// Drift describes how the running container differs from the current configuration
+type Drift struct {
+	Changes []string
+}
+
+// CheckDrift compares the configuration and image the container was created
+// with against the current ones. It returns nil if the container is up to date
+// or does not exist.
+func (m *Manager) CheckDrift(ctx context.Context, cfg *config.Config) (*Drift, error) {
+	inspect, err := m.client.ContainerInspect(ctx, m.containerName)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	imageID, err := m.imageID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := inspect.Config.Labels
+	if labels[LabelConfigHash] == cfg.Hash(imageID) {
+		return nil, nil
+	}
+
+	drift := &Drift{}
+	oldConfigJSON, ok := labels[LabelConfig]
+	if !ok {
+		drift.Changes = append(drift.Changes, "container was created by an older version of claudeway")
+		return drift, nil
+	}
+
+	var oldConfig config.Config
+	if err := json.Unmarshal([]byte(oldConfigJSON), &oldConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse config label: %w", err)
+	}
+
+	drift.Changes = config.Diff(&oldConfig, cfg)
+	// Containers created from an init image record the image it is based on
+	oldImageID := inspect.Image
+	if baseID, ok := labels[LabelBaseImage]; ok {
+		oldImageID = baseID
+	}
+	if imageID != "" && oldImageID != imageID {
+		drift.Changes = append(drift.Changes, fmt.Sprintf("image: %s -> %s", ShortID(oldImageID), ShortID(imageID)))
+	}
+	return drift, nil
+}
+
+// containerLabels returns the labels identifying a container created from cfg
+func (m *Manager) containerLabels(ctx context.Context, cfg *config.Config) (map[string]string, error) {
+	imageID, err := m.imageID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	configJSON, err := json.Marshal(cfg.Canonical())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	labels := map[string]string{
+		LabelManaged:    "true",
+		LabelProject:    m.workDir,
+		LabelConfig:     string(configJSON),
+		LabelConfigHash: cfg.Hash(imageID),
+		LabelBaseImage:  imageID,
+	}
+	if m.ephemeral {
+		labels[LabelEphemeral] = "true"
+	}
+	if name := cfg.AgentName(); name != "" {
+		labels[LabelAgent] = name
+		labels[LabelAgentSource] = "config"
+		if cfg.AgentOverridden() {
+			labels[LabelAgentSource] = "cli"
+		}
+	}
+	return labels, nil
+}
+
+// ContainerAgent returns the agent preset the container was created with by
+// 'claudeway agent', empty if it has none, took it from the configuration or
+// does not exist
+func (m *Manager) ContainerAgent(ctx context.Context) (string, error) {
+	inspect, err := m.client.ContainerInspect(ctx, m.containerName)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to inspect container: %w", err)
+	}
+	if inspect.Config.Labels[LabelAgentSource] != "cli" {
+		return "", nil
+	}
+	return inspect.Config.Labels[LabelAgent], nil
+}
+
+// imageID returns the ID of the claudeway image, or an empty string if it has
+// not been built yet
+func (m *Manager) imageID(ctx context.Context) (string, error) {
+	inspect, _, err := m.client.ImageInspectWithRaw(ctx, ImageName)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to inspect image: %w", err)
+	}
+	return inspect.ID, nil
+}
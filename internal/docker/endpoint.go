@@ -0,0 +1,170 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// Endpoint is the daemon the runtime connects to
+type Endpoint struct {
+	// Context is the Docker context it was taken from, empty if it came
+	// from --host or the environment
+	Context string `json:"context,omitempty"`
+	// Host is the daemon address, empty for the default socket
+	Host          string `json:"host,omitempty"`
+	SkipTLSVerify bool   `json:"-"`
+	// TLSDir holds ca.pem, cert.pem and key.pem of the context, if any
+	TLSDir string `json:"-"`
+}
+
+func (e *Endpoint) String() string {
+	host := e.Host
+	if host == "" {
+		host = "default socket"
+	}
+	if e.Context != "" {
+		return fmt.Sprintf("%s (context %s)", host, e.Context)
+	}
+	return host
+}
+
+// Remote reports whether the daemon runs on another machine, so paths of
+// this machine cannot be bind-mounted
+func (e *Endpoint) Remote() bool {
+	u, err := url.Parse(e.Host)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "ssh":
+		return true
+	case "tcp", "http", "https":
+		host := u.Hostname()
+		if host == "localhost" {
+			return false
+		}
+		ip := net.ParseIP(host)
+		return ip == nil || !ip.IsLoopback()
+	}
+	return false
+}
+
+// Endpoint selection from the global --context and --host flags
+var (
+	endpointContext string
+	endpointHost    string
+)
+
+// SetEndpoint selects the daemon by Docker context or by address, overriding
+// the environment and the current context of the Docker CLI. Empty values
+// are ignored.
+func SetEndpoint(contextName, host string) {
+	endpointContext = contextName
+	endpointHost = host
+}
+
+// ResolveEndpoint returns the daemon to connect to. Like the Docker CLI it
+// takes --host, --context, DOCKER_HOST, DOCKER_CONTEXT and the current
+// context in that order. Without any of them Podman's CONTAINER_HOST and
+// sockets are tried before the default Docker socket.
+func ResolveEndpoint() (*Endpoint, error) {
+	if endpointHost != "" {
+		return &Endpoint{Host: endpointHost}, nil
+	}
+	if endpointContext != "" {
+		return contextEndpoint(endpointContext)
+	}
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return &Endpoint{Host: host}, nil
+	}
+	if name := os.Getenv("DOCKER_CONTEXT"); name != "" {
+		return contextEndpoint(name)
+	}
+	if name := currentContext(); name != "" {
+		return contextEndpoint(name)
+	}
+
+	host, err := podmanHost()
+	if err != nil {
+		return nil, err
+	}
+	return &Endpoint{Host: host}, nil
+}
+
+// dockerConfigDir returns the Docker CLI's configuration directory
+func dockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker")
+}
+
+// currentContext returns the context selected with 'docker context use', or
+// "" for the default context
+func currentContext() string {
+	data, err := os.ReadFile(filepath.Join(dockerConfigDir(), "config.json"))
+	if err != nil {
+		return ""
+	}
+	var cliConfig struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if err := json.Unmarshal(data, &cliConfig); err != nil || cliConfig.CurrentContext == "default" {
+		return ""
+	}
+	return cliConfig.CurrentContext
+}
+
+// contextEndpoint reads the Docker endpoint of a context from the context
+// store of the Docker CLI
+func contextEndpoint(name string) (*Endpoint, error) {
+	if name == "default" {
+		host, err := podmanHost()
+		if err != nil {
+			return nil, err
+		}
+		return &Endpoint{Context: name, Host: host}, nil
+	}
+
+	// The store keeps each context in a directory named after its digest
+	sum := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(sum[:])
+	data, err := os.ReadFile(filepath.Join(dockerConfigDir(), "contexts", "meta", id, "meta.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("docker context %q not found", name)
+		}
+		return nil, fmt.Errorf("failed to read docker context %q: %w", name, err)
+	}
+
+	var meta struct {
+		Endpoints map[string]struct {
+			Host          string `json:"Host"`
+			SkipTLSVerify bool   `json:"SkipTLSVerify"`
+		} `json:"Endpoints"`
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse docker context %q: %w", name, err)
+	}
+	docker, ok := meta.Endpoints["docker"]
+	if !ok {
+		return nil, fmt.Errorf("docker context %q has no docker endpoint", name)
+	}
+
+	endpoint := &Endpoint{Context: name, Host: docker.Host, SkipTLSVerify: docker.SkipTLSVerify}
+	tlsDir := filepath.Join(dockerConfigDir(), "contexts", "tls", id, "docker")
+	if _, err := os.Stat(tlsDir); err == nil {
+		endpoint.TLSDir = tlsDir
+	}
+	return endpoint, nil
+}
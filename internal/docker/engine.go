@@ -0,0 +1,64 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Container engines behind the Docker API
+const (
+	EngineDocker = "docker"
+	EnginePodman = "podman"
+)
+
+// Engine describes the container engine the runtime talks to
+type Engine struct {
+	Name     string `json:"name"`
+	Rootless bool   `json:"rootless,omitempty"`
+}
+
+func (e Engine) String() string {
+	if e.Rootless {
+		return e.Name + " (rootless)"
+	}
+	return e.Name
+}
+
+// KeepsUserID reports whether containers run in a user namespace that keeps
+// the host user's UID and GID, like podman run --userns=keep-id. Podman then
+// adds the account itself and root in the container is not root on the host.
+func (e Engine) KeepsUserID() bool {
+	return e.Name == EnginePodman && e.Rootless
+}
+
+// Engine detects the container engine once and remembers it
+func (m *Manager) Engine(ctx context.Context) (Engine, error) {
+	if m.engine != nil {
+		return *m.engine, nil
+	}
+
+	version, err := m.client.ServerVersion(ctx)
+	if err != nil {
+		return Engine{}, fmt.Errorf("failed to get server version: %w", err)
+	}
+	engine := Engine{Name: EngineDocker}
+	for _, component := range version.Components {
+		if strings.Contains(strings.ToLower(component.Name), EnginePodman) {
+			engine.Name = EnginePodman
+		}
+	}
+
+	info, err := m.client.Info(ctx)
+	if err != nil {
+		return Engine{}, fmt.Errorf("failed to get server info: %w", err)
+	}
+	for _, option := range info.SecurityOptions {
+		if strings.Contains(option, "name=rootless") {
+			engine.Rootless = true
+		}
+	}
+
+	m.engine = &engine
+	return engine, nil
+}
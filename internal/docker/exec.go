@@ -0,0 +1,257 @@
+package docker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/common-creation/claudeway/internal/term"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// ExitError is returned when a command in the container exits with a
+// non-zero status
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with code %d", e.Code)
+}
+
+// ExecOptions describes a command to run in the container
+type ExecOptions struct {
+	Cmd []string
+	Env []string
+	// User is a name or uid[:gid] in the container, root if empty
+	User string
+	// WorkingDir defaults to the container's working directory
+	WorkingDir string
+	// Tty allocates a pseudo terminal and puts the local terminal in raw
+	// mode. Stdin must be a terminal in that case.
+	Tty    bool
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Exec runs a command in the container, streams its input and output and
+// returns its exit code. SIGINT, SIGTERM and SIGHUP received meanwhile are
+// forwarded to the command, and it is terminated when ctx is cancelled.
+// With a TTY, the local terminal follows the session's size and is restored
+// on signals and panics. The command is started through /bin/sh to record
+// its PID, so the image must provide it.
+func (m *Manager) Exec(ctx context.Context, options ExecOptions) (int, error) {
+	// Run the command through a shell that records its PID inside the
+	// container, the exec API has no way to signal the process
+	pidFile, err := execPIDFile()
+	if err != nil {
+		return 0, err
+	}
+	cmd := append([]string{"/bin/sh", "-c", `echo $$ > "$0"; exec "$@"`, pidFile}, options.Cmd...)
+	// The shell is replaced by the command, so the file is removed from here
+	defer m.removeExecPIDFile(pidFile)
+
+	execConfig := types.ExecConfig{
+		Cmd:          cmd,
+		AttachStdin:  options.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          options.Tty,
+		Env:          options.Env,
+		User:         options.User,
+		WorkingDir:   options.WorkingDir,
+	}
+
+	execResp, err := m.client.ContainerExecCreate(ctx, m.containerName, execConfig)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	resp, err := m.client.ContainerExecAttach(ctx, execResp.ID, types.ExecStartCheck{
+		Tty: options.Tty,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to attach to exec: %w", err)
+	}
+	defer resp.Close()
+
+	restore := func() {}
+	if options.Tty {
+		// Set terminal to raw mode
+		oldState, err := term.MakeRaw(os.Stdin.Fd())
+		if err != nil {
+			return 0, fmt.Errorf("failed to set raw terminal: %w", err)
+		}
+		var once sync.Once
+		restore = func() {
+			once.Do(func() { term.Restore(os.Stdin.Fd(), oldState) })
+		}
+		defer restore()
+
+		// Keep the exec's TTY size in sync with the local terminal
+		resize := func() {
+			if size, err := term.GetSize(os.Stdout.Fd()); err == nil {
+				m.client.ContainerExecResize(ctx, execResp.ID, types.ResizeOptions{
+					Height: uint(size.Height),
+					Width:  uint(size.Width),
+				})
+			}
+		}
+		resize()
+
+		resized := make(chan struct{}, 1)
+		stopResize := term.NotifyResize(resized)
+		defer func() {
+			stopResize()
+			close(resized)
+		}()
+		go func() {
+			for range resized {
+				resize()
+			}
+		}()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	if options.Stdin != nil {
+		go func() {
+			defer restoreOnPanic(restore)
+			io.Copy(resp.Conn, options.Stdin)
+			// Forward EOF so the command sees the end of its input
+			resp.CloseWrite()
+		}()
+	}
+
+	// The output ends when the command exits
+	stdout := options.Stdout
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	stderr := options.Stderr
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	outputDone := make(chan error, 1)
+	go func() {
+		defer restoreOnPanic(restore)
+		var err error
+		if options.Tty {
+			_, err = io.Copy(stdout, resp.Reader)
+		} else {
+			// Without a TTY, stdout and stderr are multiplexed on one stream
+			_, err = stdcopy.StdCopy(stdout, stderr, resp.Reader)
+		}
+		outputDone <- err
+	}()
+
+	for {
+		select {
+		case err := <-outputDone:
+			if err != nil {
+				return 0, fmt.Errorf("failed to read exec output: %w", err)
+			}
+			return m.waitExec(ctx, execResp.ID)
+
+		case sig := <-signals:
+			if err := m.signalExec(ctx, pidFile, sig); err != nil {
+				fmt.Fprintf(m.errOut(), "Warning: failed to forward %v: %v\n", sig, err)
+			}
+			if options.Tty {
+				// In raw mode Ctrl-C arrives as input, so a signal means the
+				// session itself is being terminated
+				restore()
+				return 128 + int(sig.(syscall.Signal)), nil
+			}
+
+		case <-ctx.Done():
+			// Do not leave the command running, e.g. when a timeout expired
+			if err := m.signalExec(context.Background(), pidFile, syscall.SIGTERM); err != nil {
+				fmt.Fprintf(m.errOut(), "Warning: failed to stop command: %v\n", err)
+			}
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// signalExec sends sig to a command started by Exec
+func (m *Manager) signalExec(ctx context.Context, pidFile string, sig os.Signal) error {
+	name, ok := signalNames[sig]
+	if !ok {
+		return fmt.Errorf("unsupported signal %v", sig)
+	}
+
+	exitCode, err := m.execExitCode(ctx, []string{"/bin/sh", "-c", fmt.Sprintf(`kill -%s "$(cat %s)"`, name, pidFile)})
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("kill exited with code %d", exitCode)
+	}
+	return nil
+}
+
+// removeExecPIDFile removes the PID file of a command started by Exec
+// without waiting for it
+func (m *Manager) removeExecPIDFile(pidFile string) {
+	ctx := context.Background()
+	execResp, err := m.client.ContainerExecCreate(ctx, m.containerName, types.ExecConfig{
+		Cmd: []string{"rm", "-f", pidFile},
+	})
+	if err == nil {
+		m.client.ContainerExecStart(ctx, execResp.ID, types.ExecStartCheck{})
+	}
+}
+
+var signalNames = map[os.Signal]string{
+	syscall.SIGINT:  "INT",
+	syscall.SIGTERM: "TERM",
+	syscall.SIGHUP:  "HUP",
+}
+
+func execPIDFile() (string, error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate pid file name: %w", err)
+	}
+	return fmt.Sprintf("/tmp/.claudeway-exec-%s.pid", hex.EncodeToString(suffix)), nil
+}
+
+// restoreOnPanic restores the terminal before a panic in a goroutine takes
+// down the process, deferred calls of other goroutines do not run then
+func restoreOnPanic(restore func()) {
+	if r := recover(); r != nil {
+		restore()
+		panic(r)
+	}
+}
+
+// waitExec waits until an exec process has exited and returns its exit code
+func (m *Manager) waitExec(ctx context.Context, execID string) (int, error) {
+	for {
+		inspect, err := m.client.ContainerExecInspect(ctx, execID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to inspect exec: %w", err)
+		}
+		if !inspect.Running {
+			return inspect.ExitCode, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package fake
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/common-creation/claudeway/internal/guest"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Container is a container of the fake runtime
+type Container struct {
+	ID         string
+	Name       string
+	ImageID    string
+	Config     container.Config
+	HostConfig container.HostConfig
+	Created    time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Running    bool
+	ExitCode   int
+	// Files holds the files written into the container by absolute path
+	Files map[string][]byte
+	// Logs is the output of the container
+	Logs bytes.Buffer
+}
+
+func (r *Runtime) lookup(name string) *Container {
+	if c, ok := r.containers[name]; ok {
+		return c
+	}
+	for _, c := range r.containers {
+		if c.ID == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (r *Runtime) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *specs.Platform, containerName string) (container.ContainerCreateCreatedBody, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.containers[containerName]; ok {
+		return container.ContainerCreateCreatedBody{}, fmt.Errorf("Conflict. The container name %q is already in use", "/"+containerName)
+	}
+	img := r.findImage(config.Image)
+	if img == nil {
+		return container.ContainerCreateCreatedBody{}, notFound("image", config.Image)
+	}
+
+	c := &Container{
+		ID:      newID(),
+		Name:    containerName,
+		ImageID: img.ID,
+		Config:  *config,
+		Created: time.Now(),
+		Files:   map[string][]byte{},
+	}
+	if hostConfig != nil {
+		c.HostConfig = *hostConfig
+	}
+	r.containers[containerName] = c
+	return container.ContainerCreateCreatedBody{ID: c.ID}, nil
+}
+
+func (r *Runtime) ContainerStart(ctx context.Context, containerID string, options types.ContainerStartOptions) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.lookup(containerID)
+	if c == nil {
+		return notFound("container", containerID)
+	}
+	if c.Running {
+		return nil
+	}
+	c.Running = true
+	c.StartedAt = time.Now()
+	c.ExitCode = 0
+	r.runGuest(c)
+	return nil
+}
+
+// runGuest does what the guest agent reports when a container starts
+func (r *Runtime) runGuest(c *Container) {
+	var spec guest.Spec
+	if data, ok := c.Files[guest.SpecPath]; ok {
+		json.Unmarshal(data, &spec)
+	}
+
+	var events []guest.Event
+	emit := func(event guest.Event) {
+		event.Time = guest.Now()
+		events = append(events, event)
+	}
+	defer func() {
+		var buf bytes.Buffer
+		for _, event := range events {
+			data, _ := json.Marshal(event)
+			buf.Write(append(data, '\n'))
+		}
+		c.Files[guest.StatusFile] = buf.Bytes()
+	}()
+
+	emit(guest.Event{Event: "begin"})
+	total := len(spec.Init)
+	if spec.User != nil {
+		total++
+	}
+
+	number := 0
+	if u := spec.User; u != nil {
+		number++
+		emit(guest.Event{Event: "start", Step: number, Total: total, Name: "Set up user " + u.Name})
+		identity := guest.Identity{Name: u.Name, UID: u.UID, GID: u.GID, Home: u.Home, Group: u.Name, Groups: u.Groups}
+		data, _ := json.Marshal(identity)
+		c.Files[guest.IdentityFile] = data
+		emit(guest.Event{Event: "end", Step: number})
+		emit(guest.Event{Event: "user_ready"})
+	}
+
+	for _, step := range spec.Init {
+		number++
+		emit(guest.Event{Event: "start", Step: number, Total: total, Name: step.Name})
+		fmt.Fprintf(&c.Logs, "Running: %s\n", step.Name)
+		code := 0
+		if r.StepResult != nil {
+			code = r.StepResult(step)
+		}
+		emit(guest.Event{Event: "end", Step: number, ExitCode: code, Ignored: code != 0 && step.ContinueOnError})
+		if code != 0 && !step.ContinueOnError {
+			fmt.Fprintln(&c.Logs, "Claudeway initialization failed.")
+			emit(guest.Event{Event: "failed", Step: number})
+			c.Running = false
+			c.ExitCode = 1
+			c.FinishedAt = time.Now()
+			return
+		}
+	}
+
+	c.Files[guest.InitMarker] = []byte("fake\n")
+	fmt.Fprintln(&c.Logs, "Claudeway initialization complete.")
+	emit(guest.Event{Event: "complete"})
+}
+
+func (r *Runtime) ContainerStop(ctx context.Context, containerID string, timeout *time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.lookup(containerID)
+	if c == nil {
+		return notFound("container", containerID)
+	}
+	if c.Running {
+		c.Running = false
+		c.FinishedAt = time.Now()
+	}
+	return nil
+}
+
+func (r *Runtime) ContainerRemove(ctx context.Context, containerID string, options types.ContainerRemoveOptions) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.lookup(containerID)
+	if c == nil {
+		return notFound("container", containerID)
+	}
+	if c.Running && !options.Force {
+		return fmt.Errorf("You cannot remove a running container %s. Stop the container before attempting removal or force remove", c.ID)
+	}
+	delete(r.containers, c.Name)
+	return nil
+}
+
+func (r *Runtime) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.lookup(containerID)
+	if c == nil {
+		return types.ContainerJSON{}, notFound("container", containerID)
+	}
+
+	config := c.Config
+	hostConfig := c.HostConfig
+	inspect := types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:      c.ID,
+			Name:    "/" + c.Name,
+			Created: c.Created.Format(time.RFC3339Nano),
+			Image:   c.ImageID,
+			State: &types.ContainerState{
+				Status:     c.status(),
+				Running:    c.Running,
+				ExitCode:   c.ExitCode,
+				StartedAt:  formatTime(c.StartedAt),
+				FinishedAt: formatTime(c.FinishedAt),
+			},
+			HostConfig: &hostConfig,
+		},
+		Config: &config,
+	}
+	for _, m := range c.HostConfig.Mounts {
+		inspect.Mounts = append(inspect.Mounts, types.MountPoint{
+			Type:        m.Type,
+			Name:        mountName(m),
+			Source:      m.Source,
+			Destination: m.Target,
+			RW:          !m.ReadOnly,
+		})
+	}
+	return inspect, nil
+}
+
+func (r *Runtime) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var list []types.Container
+	for _, c := range r.containers {
+		if !c.Running && !options.All {
+			continue
+		}
+		if !matchName(options.Filters, c.Name) || !matchLabels(options.Filters, c.Config.Labels) {
+			continue
+		}
+		list = append(list, types.Container{
+			ID:      c.ID,
+			Names:   []string{"/" + c.Name},
+			Image:   c.Config.Image,
+			ImageID: c.ImageID,
+			Created: c.Created.Unix(),
+			Labels:  c.Config.Labels,
+			State:   c.status(),
+		})
+	}
+	return list, nil
+}
+
+// ContainerLogs returns the logs written so far, also when following them
+func (r *Runtime) ContainerLogs(ctx context.Context, containerID string, options types.ContainerLogsOptions) (io.ReadCloser, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.lookup(containerID)
+	if c == nil {
+		return nil, notFound("container", containerID)
+	}
+	return io.NopCloser(bytes.NewReader(append([]byte(nil), c.Logs.Bytes()...))), nil
+}
+
+// ContainerStats reports an idle container
+func (r *Runtime) ContainerStats(ctx context.Context, containerID string, stream bool) (types.ContainerStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.lookup(containerID) == nil {
+		return types.ContainerStats{}, notFound("container", containerID)
+	}
+	data, _ := json.Marshal(types.StatsJSON{})
+	return types.ContainerStats{Body: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+// CopyToContainer extracts the files of a tar archive into the container
+func (r *Runtime) CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader, options types.CopyToContainerOptions) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.lookup(containerID)
+	if c == nil {
+		return notFound("container", containerID)
+	}
+
+	tr := tar.NewReader(content)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		c.Files[path.Join(dstPath, header.Name)] = data
+	}
+}
+
+// CopyFromContainer returns a file of the container as a tar archive
+func (r *Runtime) CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, types.ContainerPathStat, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.lookup(containerID)
+	if c == nil {
+		return nil, types.ContainerPathStat{}, notFound("container", containerID)
+	}
+	data, ok := c.Files[path.Clean(srcPath)]
+	if !ok {
+		return nil, types.ContainerPathStat{}, notFound("file", srcPath)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	name := path.Base(srcPath)
+	tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))})
+	tw.Write(data)
+	tw.Close()
+	stat := types.ContainerPathStat{Name: name, Size: int64(len(data)), Mode: 0644}
+	return io.NopCloser(&buf), stat, nil
+}
+
+func (c *Container) status() string {
+	switch {
+	case c.Running:
+		return "running"
+	case c.StartedAt.IsZero():
+		return "created"
+	default:
+		return "exited"
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "0001-01-01T00:00:00Z"
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func mountName(m mount.Mount) string {
+	if m.Type == mount.TypeVolume {
+		return m.Source
+	}
+	return ""
+}
+
+// hasFile reports whether a file was written into the container
+func (c *Container) hasFile(name string) bool {
+	_, ok := c.Files[path.Clean(name)]
+	return ok
+}
@@ -0,0 +1,167 @@
+package fake
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// execState is an exec instance of a container
+type execState struct {
+	container string
+	config    types.ExecConfig
+	running   bool
+	exitCode  int
+}
+
+func (r *Runtime) ContainerExecCreate(ctx context.Context, container string, config types.ExecConfig) (types.IDResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.lookup(container)
+	if c == nil {
+		return types.IDResponse{}, notFound("container", container)
+	}
+	if !c.Running {
+		return types.IDResponse{}, errNotRunning(c)
+	}
+	id := newID()
+	r.execs[id] = &execState{container: c.Name, config: config}
+	return types.IDResponse{ID: id}, nil
+}
+
+func (r *Runtime) ContainerExecStart(ctx context.Context, execID string, config types.ExecStartCheck) error {
+	state, err := r.startExec(execID)
+	if err != nil {
+		return err
+	}
+	r.finishExec(state, r.runExec(state, eofReader{}, io.Discard, io.Discard))
+	return nil
+}
+
+// ContainerExecAttach starts the exec and streams its output over an in
+// memory connection, multiplexed like the daemon does without a TTY
+func (r *Runtime) ContainerExecAttach(ctx context.Context, execID string, config types.ExecStartCheck) (types.HijackedResponse, error) {
+	state, err := r.startExec(execID)
+	if err != nil {
+		return types.HijackedResponse{}, err
+	}
+
+	inputReader, inputWriter := io.Pipe()
+	outputReader, outputWriter := io.Pipe()
+	conn := &hijackedConn{input: inputWriter, output: outputReader}
+	go func() {
+		var stdin io.Reader = inputReader
+		if !state.config.AttachStdin {
+			stdin = eofReader{}
+			go io.Copy(io.Discard, inputReader)
+		}
+
+		stdout, stderr := io.Writer(outputWriter), io.Writer(outputWriter)
+		if !state.config.Tty {
+			stdout = stdcopy.NewStdWriter(outputWriter, stdcopy.Stdout)
+			stderr = stdcopy.NewStdWriter(outputWriter, stdcopy.Stderr)
+		}
+		exitCode := r.runExec(state, stdin, stdout, stderr)
+		// Input the command did not read is discarded
+		inputReader.Close()
+		r.finishExec(state, exitCode)
+		outputWriter.Close()
+	}()
+	return types.HijackedResponse{Conn: conn, Reader: bufio.NewReader(conn)}, nil
+}
+
+// hijackedConn is the client end of an attached exec. Unlike net.Pipe its
+// input can be closed on its own, like a TCP connection's.
+type hijackedConn struct {
+	input  *io.PipeWriter
+	output *io.PipeReader
+}
+
+func (c *hijackedConn) Read(p []byte) (int, error)  { return c.output.Read(p) }
+func (c *hijackedConn) Write(p []byte) (int, error) { return c.input.Write(p) }
+func (c *hijackedConn) CloseWrite() error           { return c.input.Close() }
+
+func (c *hijackedConn) Close() error {
+	c.input.Close()
+	return c.output.Close()
+}
+
+func (c *hijackedConn) LocalAddr() net.Addr                { return fakeAddr{} }
+func (c *hijackedConn) RemoteAddr() net.Addr               { return fakeAddr{} }
+func (c *hijackedConn) SetDeadline(t time.Time) error      { return nil }
+func (c *hijackedConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *hijackedConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type fakeAddr struct{}
+
+func (fakeAddr) Network() string { return "fake" }
+func (fakeAddr) String() string  { return "fake" }
+
+type eofReader struct{}
+
+func (eofReader) Read(p []byte) (int, error) { return 0, io.EOF }
+
+func (r *Runtime) ContainerExecInspect(ctx context.Context, execID string) (types.ContainerExecInspect, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.execs[execID]
+	if !ok {
+		return types.ContainerExecInspect{}, notFound("exec instance", execID)
+	}
+	return types.ContainerExecInspect{
+		ExecID:   execID,
+		Running:  state.running,
+		ExitCode: state.exitCode,
+	}, nil
+}
+
+func (r *Runtime) ContainerExecResize(ctx context.Context, execID string, options types.ResizeOptions) error {
+	return nil
+}
+
+func (r *Runtime) startExec(execID string) (*execState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.execs[execID]
+	if !ok {
+		return nil, notFound("exec instance", execID)
+	}
+	state.running = true
+	r.execLog = append(r.execLog, Exec{Container: state.container, Config: state.config})
+	return state, nil
+}
+
+func (r *Runtime) finishExec(state *execState, exitCode int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state.running = false
+	state.exitCode = exitCode
+}
+
+// runExec runs the command with the handler. Without one, test -f and
+// test -e look at the files written into the container and everything else
+// succeeds.
+func (r *Runtime) runExec(state *execState, stdin io.Reader, stdout, stderr io.Writer) int {
+	if r.ExecHandler != nil {
+		return r.ExecHandler(state.container, state.config, stdin, stdout, stderr)
+	}
+
+	cmd := Command(state.config)
+	if len(cmd) == 3 && cmd[0] == "test" && (cmd[1] == "-f" || cmd[1] == "-e") {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if c := r.lookup(state.container); c != nil && c.hasFile(cmd[2]) {
+			return 0
+		}
+		return 1
+	}
+	return 0
+}
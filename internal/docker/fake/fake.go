@@ -0,0 +1,198 @@
+// Package fake implements docker.Runtime in memory, so the flows of
+// claudeway can run deterministically without a daemon.
+//
+// Containers do not run anything. Starting one plays the guest agent: it
+// reads the spec written by the manager and reports every init step as
+// succeeded, or as StepResult decides. Commands run through exec are handed
+// to ExecHandler.
+package fake
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/common-creation/claudeway/internal/docker"
+	"github.com/common-creation/claudeway/internal/guest"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+)
+
+var _ docker.Runtime = (*Runtime)(nil)
+
+// ExecHandler runs a command started through exec and returns its exit code.
+// stdin is the caller's input, if attached. Output written to stdout and
+// stderr is streamed back to the caller.
+type ExecHandler func(container string, config types.ExecConfig, stdin io.Reader, stdout, stderr io.Writer) int
+
+// Runtime is an in-memory container runtime. The zero value is not usable,
+// create one with New.
+type Runtime struct {
+	// ExecHandler runs exec commands, they succeed without output if nil
+	ExecHandler ExecHandler
+	// StepResult returns the exit code of an init step, 0 if nil
+	StepResult func(step guest.Step) int
+	// Engine is what the runtime reports to be, Docker by default
+	Engine docker.Engine
+
+	mu         sync.Mutex
+	containers map[string]*Container
+	images     map[string]*image
+	volumes    map[string]*types.Volume
+	execs      map[string]*execState
+	execLog    []Exec
+}
+
+// Exec is a command that was run in a container
+type Exec struct {
+	Container string
+	Config    types.ExecConfig
+}
+
+// New returns a runtime that already has the claudeway image, so it is not
+// built first
+func New() *Runtime {
+	r := &Runtime{
+		Engine:     docker.Engine{Name: docker.EngineDocker},
+		containers: map[string]*Container{},
+		images:     map[string]*image{},
+		volumes:    map[string]*types.Volume{},
+		execs:      map[string]*execState{},
+	}
+	r.addImage([]string{docker.ImageName}, map[string]string{docker.LabelManaged: "true"})
+	return r
+}
+
+// Install makes the docker package use r, until the returned function is
+// called
+func (r *Runtime) Install() (restore func()) {
+	previous := docker.NewRuntime
+	docker.NewRuntime = func() (docker.Runtime, error) { return r, nil }
+	return func() { docker.NewRuntime = previous }
+}
+
+// Container returns the container with the given name or ID, nil if there
+// is none
+func (r *Runtime) Container(name string) *Container {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lookup(name)
+}
+
+// Containers returns the names of all containers
+func (r *Runtime) Containers() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var names []string
+	for name := range r.containers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Execs returns the commands run so far, in order
+func (r *Runtime) Execs() []Exec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Exec(nil), r.execLog...)
+}
+
+// Command returns the command of an exec without the shell that
+// docker.Manager wraps around it to record its PID
+func Command(config types.ExecConfig) []string {
+	cmd := config.Cmd
+	if len(cmd) >= 4 && cmd[0] == "/bin/sh" && cmd[1] == "-c" && strings.Contains(cmd[2], `exec "$@"`) {
+		return cmd[4:]
+	}
+	return cmd
+}
+
+func (r *Runtime) ServerVersion(ctx context.Context) (types.Version, error) {
+	return types.Version{
+		Version:    "fake",
+		Components: []types.ComponentVersion{{Name: r.Engine.Name + " fake", Version: "fake"}},
+	}, nil
+}
+
+func (r *Runtime) Info(ctx context.Context) (types.Info, error) {
+	info := types.Info{Name: "fake"}
+	if r.Engine.Rootless {
+		info.SecurityOptions = []string{"name=rootless"}
+	}
+	return info, nil
+}
+
+func (r *Runtime) DiskUsage(ctx context.Context) (types.DiskUsage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var usage types.DiskUsage
+	for _, volume := range r.volumes {
+		v := *volume
+		v.UsageData = &types.VolumeUsageData{RefCount: 0, Size: 0}
+		usage.Volumes = append(usage.Volumes, &v)
+	}
+	return usage, nil
+}
+
+func (r *Runtime) Close() error {
+	return nil
+}
+
+// notFoundError satisfies client.IsErrNotFound
+type notFoundError struct {
+	what string
+}
+
+func (e notFoundError) Error() string {
+	return fmt.Sprintf("No such %s", e.what)
+}
+
+func (e notFoundError) NotFound() bool {
+	return true
+}
+
+func errNotRunning(c *Container) error {
+	return fmt.Errorf("Container %s is not running", c.ID)
+}
+
+func notFound(kind, name string) error {
+	return notFoundError{what: kind + ": " + name}
+}
+
+func newID() string {
+	id := make([]byte, 32)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// matchLabels reports whether labels match all label filters
+func matchLabels(args filters.Args, labels map[string]string) bool {
+	for _, filter := range args.Get("label") {
+		key, value, hasValue := strings.Cut(filter, "=")
+		actual, ok := labels[key]
+		if !ok || (hasValue && actual != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchName reports whether name matches any name filter, which are regular
+// expressions like the daemon's
+func matchName(args filters.Args, name string) bool {
+	patterns := args.Get("name")
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if re, err := regexp.Compile(pattern); err == nil && re.MatchString("/"+name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,268 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/common-creation/claudeway/internal/guest"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	volumetypes "github.com/docker/docker/api/types/volume"
+)
+
+// image is an image of the fake runtime. Every image runs the guest agent.
+type image struct {
+	ID          string
+	RepoTags    []string
+	RepoDigests []string
+	Labels      map[string]string
+	Created     time.Time
+}
+
+// addImage adds an image, taking its tags from older images
+func (r *Runtime) addImage(tags []string, labels map[string]string) *image {
+	for _, tag := range tags {
+		if old := r.findImage(tag); old != nil {
+			old.RepoTags = removeTag(old.RepoTags, tag)
+		}
+	}
+	img := &image{ID: "sha256:" + newID(), RepoTags: tags, Labels: labels, Created: time.Now()}
+	r.images[img.ID] = img
+	return img
+}
+
+// findImage finds an image by tag or ID
+func (r *Runtime) findImage(ref string) *image {
+	for _, img := range r.images {
+		if img.ID == ref || strings.TrimPrefix(img.ID, "sha256:") == ref {
+			return img
+		}
+	}
+	if !strings.Contains(ref, ":") {
+		ref += ":latest"
+	}
+	for _, img := range r.images {
+		for _, tag := range img.RepoTags {
+			if tag == ref {
+				return img
+			}
+		}
+	}
+	return nil
+}
+
+func removeTag(tags []string, tag string) []string {
+	var result []string
+	for _, t := range tags {
+		if t != tag {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// ImageBuild adds an image without looking at the build context
+func (r *Runtime) ImageBuild(ctx context.Context, buildContext io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error) {
+	io.Copy(io.Discard, buildContext)
+
+	r.mu.Lock()
+	img := r.addImage(options.Tags, options.Labels)
+	r.mu.Unlock()
+
+	var body bytes.Buffer
+	for _, message := range []string{
+		"Step 1/1 : FROM fake\n",
+		fmt.Sprintf("Successfully built %s\n", img.ID[len("sha256:"):len("sha256:")+12]),
+	} {
+		data, _ := json.Marshal(map[string]string{"stream": message})
+		body.Write(append(data, '\n'))
+	}
+	return types.ImageBuildResponse{Body: io.NopCloser(&body)}, nil
+}
+
+func (r *Runtime) ImageList(ctx context.Context, options types.ImageListOptions) ([]types.ImageSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dangling := options.Filters.ExactMatch("dangling", "true")
+	var list []types.ImageSummary
+	for _, img := range r.images {
+		if options.Filters.Contains("dangling") && dangling != (len(img.RepoTags) == 0) {
+			continue
+		}
+		if !matchLabels(options.Filters, img.Labels) {
+			continue
+		}
+		list = append(list, types.ImageSummary{
+			ID:          img.ID,
+			RepoTags:    img.RepoTags,
+			RepoDigests: img.RepoDigests,
+			Labels:      img.Labels,
+			Created:     img.Created.Unix(),
+		})
+	}
+	return list, nil
+}
+
+func (r *Runtime) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	img := r.findImage(imageID)
+	if img == nil {
+		return types.ImageInspect{}, nil, notFound("image", imageID)
+	}
+	inspect := types.ImageInspect{
+		ID:          img.ID,
+		RepoTags:    img.RepoTags,
+		RepoDigests: img.RepoDigests,
+		Created:     img.Created.Format(time.RFC3339Nano),
+		Config: &container.Config{
+			Labels:     img.Labels,
+			Entrypoint: []string{guest.BinaryPath},
+		},
+	}
+	raw, _ := json.Marshal(inspect)
+	return inspect, raw, nil
+}
+
+func (r *Runtime) ImageRemove(ctx context.Context, imageID string, options types.ImageRemoveOptions) ([]types.ImageDeleteResponseItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	img := r.findImage(imageID)
+	if img == nil {
+		return nil, notFound("image", imageID)
+	}
+	if !options.Force {
+		for _, c := range r.containers {
+			if c.ImageID == img.ID {
+				return nil, fmt.Errorf("conflict: unable to remove image %s, it is used by container %s", imageID, c.Name)
+			}
+		}
+	}
+	delete(r.images, img.ID)
+	return []types.ImageDeleteResponseItem{{Deleted: img.ID}}, nil
+}
+
+// ImagePull adds the image with a registry digest if it does not exist.
+// Pulling an existing image keeps it, as if the registry had no update.
+func (r *Runtime) ImagePull(ctx context.Context, refStr string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tag := refStr
+	if !strings.Contains(tag, ":") {
+		tag += ":latest"
+	}
+	if r.findImage(tag) == nil {
+		img := r.addImage([]string{tag}, nil)
+		repository := tag[:strings.LastIndex(tag, ":")]
+		img.RepoDigests = []string{repository + "@sha256:" + newID()}
+	}
+
+	data, _ := json.Marshal(map[string]string{"status": "Status: Image is up to date for " + tag})
+	return io.NopCloser(bytes.NewReader(append(data, '\n'))), nil
+}
+
+// ImageTag adds a tag to an image, taking it from the image that had it
+func (r *Runtime) ImageTag(ctx context.Context, source, target string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	img := r.findImage(source)
+	if img == nil {
+		return notFound("image", source)
+	}
+	if !strings.Contains(target, ":") {
+		target += ":latest"
+	}
+	if old := r.findImage(target); old != nil {
+		old.RepoTags = removeTag(old.RepoTags, target)
+	}
+	img.RepoTags = append(img.RepoTags, target)
+	return nil
+}
+
+func (r *Runtime) VolumeCreate(ctx context.Context, options volumetypes.VolumeCreateBody) (types.Volume, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := options.Name
+	if name == "" {
+		name = newID()
+	}
+	if volume, ok := r.volumes[name]; ok {
+		return *volume, nil
+	}
+	volume := &types.Volume{
+		Name:       name,
+		Driver:     "local",
+		Labels:     options.Labels,
+		Mountpoint: "/var/lib/docker/volumes/" + name + "/_data",
+		CreatedAt:  time.Now().Format(time.RFC3339),
+		Scope:      "local",
+	}
+	r.volumes[name] = volume
+	return *volume, nil
+}
+
+func (r *Runtime) VolumeInspect(ctx context.Context, volumeID string) (types.Volume, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	volume, ok := r.volumes[volumeID]
+	if !ok {
+		return types.Volume{}, notFound("volume", volumeID)
+	}
+	return *volume, nil
+}
+
+func (r *Runtime) VolumeList(ctx context.Context, filter filters.Args) (volumetypes.VolumeListOKBody, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var body volumetypes.VolumeListOKBody
+	for _, volume := range r.volumes {
+		if filter.ExactMatch("dangling", "true") && r.volumeInUse(volume.Name) {
+			continue
+		}
+		if !matchLabels(filter, volume.Labels) {
+			continue
+		}
+		v := *volume
+		body.Volumes = append(body.Volumes, &v)
+	}
+	return body, nil
+}
+
+func (r *Runtime) VolumeRemove(ctx context.Context, volumeID string, force bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.volumes[volumeID]; !ok {
+		return notFound("volume", volumeID)
+	}
+	if r.volumeInUse(volumeID) && !force {
+		return fmt.Errorf("remove %s: volume is in use", volumeID)
+	}
+	delete(r.volumes, volumeID)
+	return nil
+}
+
+func (r *Runtime) volumeInUse(name string) bool {
+	for _, c := range r.containers {
+		for _, m := range c.HostConfig.Mounts {
+			if mountName(m) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -4,91 +4,377 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/client"
 	"github.com/common-creation/claudeway/internal/assets"
 	"github.com/common-creation/claudeway/internal/config"
+	"github.com/common-creation/claudeway/internal/guestbin"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+const (
+	// LabelImageHash holds the hash of the build context and base images a
+	// claudeway image was built from
+	LabelImageHash = "claudeway.image-hash"
+	// LabelImageBases holds the base images of a claudeway image and their
+	// digests, as JSON
+	LabelImageBases = "claudeway.image-bases"
+
+	imageRepository = "claudeway"
 )
 
 type BuildOptions struct {
 	NoCache bool
+	// Pull pulls the base images even if they exist locally
+	Pull bool
+	// Output receives the build progress, os.Stdout if nil
+	Output io.Writer
+}
+
+// BuildResult tells which image a build ended up with
+type BuildResult struct {
+	// Tag is the content-addressed tag of the image
+	Tag string
+	// Built is set if the image was built instead of found up to date
+	Built bool
 }
 
 func BuildImage(ctx context.Context) error {
-	return BuildImageWithOptions(ctx, BuildOptions{})
+	_, err := BuildImageWithOptions(ctx, BuildOptions{})
+	return err
 }
 
-func BuildImageWithOptions(ctx context.Context, options BuildOptions) error {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+// BuildImageWithOptions builds the claudeway image unless an image for the
+// current build context and base images exists. Images are tagged with a
+// hash of both, so changed Docker assets, a new claudeway version or updated
+// base images cause a rebuild.
+func BuildImageWithOptions(ctx context.Context, options BuildOptions) (*BuildResult, error) {
+	cli, err := NewRuntime()
 	if err != nil {
-		return fmt.Errorf("failed to create docker client: %w", err)
+		return nil, err
 	}
 	defer cli.Close()
 
-	// Check if image already exists (skip if no-cache is enabled)
-	if !options.NoCache {
-		images, err := cli.ImageList(ctx, types.ImageListOptions{})
-		if err != nil {
-			return fmt.Errorf("failed to list images: %w", err)
-		}
-
-		for _, img := range images {
-			for _, tag := range img.RepoTags {
-				if tag == ImageName {
-					// Check if we're using embedded assets
-					configDir := config.GetConfigDir()
-					libDir := filepath.Join(configDir, "claudeway", "lib")
-					dockerfilePath := filepath.Join(libDir, "Dockerfile")
-					entrypointPath := filepath.Join(libDir, "entrypoint.sh")
-					
-					// If external files don't exist, we need to rebuild with embedded assets
-					_, dockerfileErr := os.Stat(dockerfilePath)
-					_, entrypointErr := os.Stat(entrypointPath)
-					if os.IsNotExist(dockerfileErr) || os.IsNotExist(entrypointErr) {
-						fmt.Println("Rebuilding image with updated embedded assets...")
-						options.NoCache = true
-						break
-					}
-					
-					// Image already exists
-					return nil
+	out := options.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	source, err := loadImageSource(ctx, cli)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create build context: %w", err)
+	}
+
+	if options.Pull {
+		for _, ref := range source.bases {
+			if pullable(ref) {
+				if err := pullImage(ctx, cli, ref, out); err != nil {
+					return nil, err
 				}
 			}
 		}
 	}
 
-	fmt.Println("Building Docker image...")
+	digests, err := baseDigests(ctx, cli, source.bases)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create build context
-	buildContext, err := createBuildContext()
+	current, _, err := cli.ImageInspectWithRaw(ctx, ImageName)
+	if err != nil && !client.IsErrNotFound(err) {
+		return nil, fmt.Errorf("failed to inspect image: %w", err)
+	}
+	if err == nil && !options.NoCache {
+		// Base images removed since the build are taken as unchanged
+		labels := imageLabels(current)
+		known := imageBases(labels)
+		for ref, digest := range digests {
+			known[ref] = digest
+		}
+		if hash := source.hash(known); labels[LabelImageHash] == hash {
+			return &BuildResult{Tag: imageTag(hash)}, nil
+		}
+		fmt.Fprintln(out, "Docker assets or base images changed, rebuilding the image...")
+	}
+
+	// Pull missing base images first so their digests are part of the hash
+	pulled := false
+	for _, ref := range source.bases {
+		if _, ok := digests[ref]; !ok && pullable(ref) {
+			if err := pullImage(ctx, cli, ref, out); err != nil {
+				return nil, err
+			}
+			pulled = true
+		}
+	}
+	if pulled {
+		if digests, err = baseDigests(ctx, cli, source.bases); err != nil {
+			return nil, err
+		}
+	}
+
+	hash := source.hash(digests)
+	tag := imageTag(hash)
+	if !options.NoCache {
+		// An image for these assets may still exist from an earlier build
+		if _, _, err := cli.ImageInspectWithRaw(ctx, tag); err == nil {
+			fmt.Fprintf(out, "Using existing image %s\n", tag)
+			if err := cli.ImageTag(ctx, tag, ImageName); err != nil {
+				return nil, fmt.Errorf("failed to tag image: %w", err)
+			}
+			return &BuildResult{Tag: tag}, nil
+		} else if !client.IsErrNotFound(err) {
+			return nil, fmt.Errorf("failed to inspect image: %w", err)
+		}
+	}
+
+	if source.libDir != "" {
+		fmt.Fprintln(out, "Using Docker assets from:", source.libDir)
+	} else {
+		fmt.Fprintln(out, "Using embedded Docker assets (run 'claudeway init --global' to create customizable assets)")
+	}
+	fmt.Fprintf(out, "Building Docker image %s...\n", tag)
+
+	basesJSON, err := json.Marshal(digests)
 	if err != nil {
-		return fmt.Errorf("failed to create build context: %w", err)
+		return nil, err
 	}
 
 	// Build options
 	buildOptions := types.ImageBuildOptions{
-		Context:    buildContext,
 		Dockerfile: "Dockerfile",
-		Tags:       []string{ImageName},
+		Tags:       []string{ImageName, tag},
 		Remove:     true,
 		NoCache:    options.NoCache,
+		Labels: map[string]string{
+			LabelManaged:    "true",
+			LabelImageHash:  hash,
+			LabelImageBases: string(basesJSON),
+		},
 	}
 
 	// Build the image
-	resp, err := cli.ImageBuild(ctx, buildContext, buildOptions)
+	resp, err := cli.ImageBuild(ctx, bytes.NewReader(source.context), buildOptions)
 	if err != nil {
-		return fmt.Errorf("failed to build image: %w", err)
+		return nil, fmt.Errorf("failed to build image: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Read build output
-	decoder := json.NewDecoder(resp.Body)
+	if err := readBuildOutput(resp.Body, out); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintln(out, "Docker image built successfully")
+	return &BuildResult{Tag: tag, Built: true}, nil
+}
+
+// ShortID returns the first 12 characters of an image ID without its
+// "sha256:" prefix, as docker shows them
+func ShortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// imageTag returns the content-addressed tag for an image hash
+func imageTag(hash string) string {
+	return imageRepository + ":" + hash[:12]
+}
+
+// imageSource is what the claudeway image is built from
+type imageSource struct {
+	context []byte
+	// bases are the images the Dockerfile's stages start from
+	bases []string
+	// libDir is the directory of external Docker assets, empty if the
+	// embedded assets are used
+	libDir string
+}
+
+// loadImageSource creates the build context of the claudeway image for the
+// daemon's architecture
+func loadImageSource(ctx context.Context, cli Runtime) (*imageSource, error) {
+	arch, err := daemonArch(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+	reader, libDir, err := createBuildContext(arch)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &imageSource{context: data, libDir: libDir}
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return source, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Name == "Dockerfile" {
+			dockerfile, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			source.bases = dockerfileBases(string(dockerfile))
+		}
+	}
+}
+
+// hash hashes the names, modes and contents of the build context's files
+// and the digests of the base images. Modification times are left out, so
+// only real changes count.
+func (s *imageSource) hash(digests map[string]string) string {
+	h := sha256.New()
+	tr := tar.NewReader(bytes.NewReader(s.context))
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		fmt.Fprintf(h, "%s\x00%o\x00%d\x00", header.Name, header.Mode, header.Size)
+		io.Copy(h, tr)
+	}
+	for _, ref := range s.bases {
+		fmt.Fprintf(h, "%s\x00%s\x00", ref, digests[ref])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// dockerfileBases returns the images the stages of a Dockerfile start from,
+// leaving out earlier stages and scratch
+func dockerfileBases(dockerfile string) []string {
+	stages := map[string]bool{"scratch": true}
+	var bases []string
+	for _, line := range strings.Split(dockerfile, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+		args := fields[1:]
+		for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		ref := args[0]
+		if !stages[strings.ToLower(ref)] && !containsString(bases, ref) {
+			bases = append(bases, ref)
+		}
+		if len(args) >= 3 && strings.EqualFold(args[1], "AS") {
+			stages[strings.ToLower(args[2])] = true
+		}
+	}
+	return bases
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// pullable reports whether a base image can be pulled before the build.
+// References using build arguments are left to the build.
+func pullable(ref string) bool {
+	return !strings.Contains(ref, "$")
+}
+
+// pullImage pulls an image from its registry
+func pullImage(ctx context.Context, cli Runtime, ref string, out io.Writer) error {
+	fmt.Fprintf(out, "Pulling %s...\n", ref)
+	resp, err := cli.ImagePull(ctx, ref, types.ImagePullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull %s: %w", ref, err)
+	}
+	defer resp.Close()
+	if err := readBuildOutput(resp, io.Discard); err != nil {
+		return fmt.Errorf("failed to pull %s: %w", ref, err)
+	}
+	return nil
+}
+
+// baseDigests returns the digests of the base images that exist locally.
+// The registry digest is used if the image has one, its ID otherwise.
+func baseDigests(ctx context.Context, cli Runtime, bases []string) (map[string]string, error) {
+	digests := make(map[string]string)
+	for _, ref := range bases {
+		if !pullable(ref) {
+			continue
+		}
+		inspect, _, err := cli.ImageInspectWithRaw(ctx, ref)
+		if err != nil {
+			if client.IsErrNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to inspect image %s: %w", ref, err)
+		}
+		digests[ref] = inspect.ID
+		repository := imageRepositoryOf(ref)
+		for _, digest := range inspect.RepoDigests {
+			if strings.HasPrefix(digest, repository+"@") {
+				digests[ref] = digest
+				break
+			}
+		}
+	}
+	return digests, nil
+}
+
+// imageRepositoryOf returns the repository of an image reference, without
+// tag or digest
+func imageRepositoryOf(ref string) string {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref = ref[:i]
+	}
+	return ref
+}
+
+func imageLabels(inspect types.ImageInspect) map[string]string {
+	if inspect.Config == nil || inspect.Config.Labels == nil {
+		return map[string]string{}
+	}
+	return inspect.Config.Labels
+}
+
+// imageBases returns the base image digests recorded in an image's labels
+func imageBases(labels map[string]string) map[string]string {
+	bases := make(map[string]string)
+	json.Unmarshal([]byte(labels[LabelImageBases]), &bases)
+	return bases
+}
+
+// readBuildOutput writes the build progress to out and returns the build error, if any
+func readBuildOutput(body io.Reader, out io.Writer) error {
+	decoder := json.NewDecoder(body)
 	for {
 		var message struct {
 			Stream string `json:"stream"`
@@ -97,7 +383,7 @@ func BuildImageWithOptions(ctx context.Context, options BuildOptions) error {
 
 		if err := decoder.Decode(&message); err != nil {
 			if err == io.EOF {
-				break
+				return nil
 			}
 			return fmt.Errorf("failed to decode build output: %w", err)
 		}
@@ -107,40 +393,59 @@ func BuildImageWithOptions(ctx context.Context, options BuildOptions) error {
 		}
 
 		if message.Stream != "" {
-			fmt.Print(message.Stream)
+			fmt.Fprint(out, message.Stream)
 		}
 	}
+}
 
-	fmt.Println("Docker image built successfully")
-	return nil
+// daemonArch returns the Go architecture name of the daemon's machine
+func daemonArch(ctx context.Context, cli Runtime) (string, error) {
+	version, err := cli.ServerVersion(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get docker version: %w", err)
+	}
+	switch version.Arch {
+	case "":
+		return runtime.GOARCH, nil
+	case "x86_64":
+		return "amd64", nil
+	case "aarch64":
+		return "arm64", nil
+	}
+	return version.Arch, nil
 }
 
-func createBuildContext() (io.Reader, error) {
-	// Check for external Docker assets first
+// createBuildContext creates the build context from the external Docker
+// assets, or the embedded ones if there are none, with the guest agent for
+// arch. libDir is the directory of the external assets.
+func createBuildContext(arch string) (buildContext io.Reader, libDir string, err error) {
+	// Check for an external Dockerfile first
 	configDir := config.GetConfigDir()
-	libDir := filepath.Join(configDir, "claudeway", "lib")
+	libDir = filepath.Join(configDir, "claudeway", "lib")
 	dockerfilePath := filepath.Join(libDir, "Dockerfile")
-	entrypointPath := filepath.Join(libDir, "entrypoint.sh")
 
-	// Check if external files exist
-	useExternalFiles := false
 	if _, err := os.Stat(dockerfilePath); err == nil {
-		if _, err := os.Stat(entrypointPath); err == nil {
-			useExternalFiles = true
-			fmt.Println("Using Docker assets from:", libDir)
-		}
-	}
-
-	if useExternalFiles {
-		return createBuildContextFromFiles(dockerfilePath, entrypointPath)
+		buildContext, err = createBuildContextFromFiles(dockerfilePath, arch)
+		return buildContext, libDir, err
 	}
 
 	// Fall back to embedded content
-	fmt.Println("Using embedded Docker assets (run 'claudeway init --global' to create customizable assets)")
-	return createBuildContextEmbedded()
+	buildContext, err = createBuildContextEmbedded(arch)
+	return buildContext, "", err
 }
 
-func createBuildContextFromFiles(dockerfilePath, entrypointPath string) (io.Reader, error) {
+func createBuildContextFromFiles(dockerfilePath, arch string) (io.Reader, error) {
+	// Dockerfiles created before the guest agent run entrypoint.sh, which
+	// claudeway no longer provides, and the first ones with it compiled the
+	// agent from source
+	dockerfile, err := os.ReadFile(dockerfilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Dockerfile: %w", err)
+	}
+	if !strings.Contains(string(dockerfile), "COPY claudeway-guest ") {
+		return nil, fmt.Errorf("%s does not install the guest agent, run 'claudeway init --global' to update it", dockerfilePath)
+	}
+
 	var buf bytes.Buffer
 	tw := tar.NewWriter(&buf)
 	defer tw.Close()
@@ -150,16 +455,14 @@ func createBuildContextFromFiles(dockerfilePath, entrypointPath string) (io.Read
 		return nil, fmt.Errorf("failed to add Dockerfile: %w", err)
 	}
 
-	// Add entrypoint script from file
-	if err := addFileToTar(tw, entrypointPath, "entrypoint.sh"); err != nil {
-		return nil, fmt.Errorf("failed to add entrypoint.sh: %w", err)
+	if err := addGuestBinary(tw, arch); err != nil {
+		return nil, err
 	}
 
-
 	return &buf, nil
 }
 
-func createBuildContextEmbedded() (io.Reader, error) {
+func createBuildContextEmbedded(arch string) (io.Reader, error) {
 	// Create tar archive
 	var buf bytes.Buffer
 	tw := tar.NewWriter(&buf)
@@ -181,26 +484,27 @@ func createBuildContextEmbedded() (io.Reader, error) {
 		return nil, fmt.Errorf("failed to write Dockerfile content: %w", err)
 	}
 
-	// Use embedded entrypoint script content
-	entrypointContent := assets.EntrypointContent
-
-	// Add entrypoint script
-	scriptHeader := &tar.Header{
-		Name: "entrypoint.sh",
-		Mode: 0755,
-		Size: int64(len(entrypointContent)),
-	}
-	if err := tw.WriteHeader(scriptHeader); err != nil {
-		return nil, fmt.Errorf("failed to write entrypoint header: %w", err)
-	}
-	if _, err := tw.Write([]byte(entrypointContent)); err != nil {
-		return nil, fmt.Errorf("failed to write entrypoint content: %w", err)
+	if err := addGuestBinary(tw, arch); err != nil {
+		return nil, err
 	}
 
-
 	return &buf, nil
 }
 
+// addGuestBinary adds the guest agent for arch as claudeway-guest, the
+// Dockerfile copies it into the image
+func addGuestBinary(tw *tar.Writer, arch string) error {
+	data, err := guestbin.Binary(arch)
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "claudeway-guest", Mode: 0755, Size: int64(len(data))}); err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
+
 func addFileToTar(tw *tar.Writer, sourcePath, destPath string) error {
 	file, err := os.Open(sourcePath)
 	if err != nil {
@@ -228,6 +532,187 @@ func addFileToTar(tw *tar.Writer, sourcePath, destPath string) error {
 	return err
 }
 
+// ImageInfo describes an image built by claudeway
+type ImageInfo struct {
+	ID      string
+	Tags    []string
+	Created time.Time
+	Size    int64
+	// Hash is the hash of the build context and base images, empty for
+	// init images and images built before it was recorded
+	Hash string
+	// Bases maps the base images to their digests at build time
+	Bases map[string]string
+	// Project is the project directory of an init image
+	Project string
+	// Containers is the number of containers using the image
+	Containers int
+}
+
+// Current reports whether the image is the one new containers use
+func (i *ImageInfo) Current() bool {
+	return containsString(i.Tags, ImageName)
+}
+
+func newImageInfo(id string, tags []string, labels map[string]string, created time.Time, size int64) *ImageInfo {
+	info := &ImageInfo{
+		ID:      id,
+		Created: created,
+		Size:    size,
+		Hash:    labels[LabelImageHash],
+		Bases:   imageBases(labels),
+		Project: labels[LabelInitImage],
+	}
+	for _, tag := range tags {
+		if tag != "<none>:<none>" {
+			info.Tags = append(info.Tags, tag)
+		}
+	}
+	sort.Strings(info.Tags)
+	return info
+}
+
+// imageUsage counts the containers using each image
+func imageUsage(ctx context.Context, cli Runtime) (map[string]int, error) {
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	usage := make(map[string]int)
+	for _, c := range containers {
+		usage[c.ImageID]++
+	}
+	return usage, nil
+}
+
+// ListImages returns the images built by claudeway, newest first
+func ListImages(ctx context.Context) ([]*ImageInfo, error) {
+	cli, err := NewRuntime()
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	images, err := cli.ImageList(ctx, types.ImageListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", LabelManaged+"=true")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	usage, err := imageUsage(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []*ImageInfo
+	for _, image := range images {
+		info := newImageInfo(image.ID, image.RepoTags, image.Labels, time.Unix(image.Created, 0), image.Size)
+		info.Containers = usage[image.ID]
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Created.After(infos[j].Created)
+	})
+	return infos, nil
+}
+
+// inspectImage finds a claudeway image by tag, ID, or the hash part of its
+// tag
+func inspectImage(ctx context.Context, cli Runtime, ref string) (*ImageInfo, error) {
+	var inspect types.ImageInspect
+	var err error
+	if !strings.Contains(ref, ":") {
+		inspect, _, err = cli.ImageInspectWithRaw(ctx, imageRepository+":"+ref)
+	}
+	if strings.Contains(ref, ":") || client.IsErrNotFound(err) {
+		inspect, _, err = cli.ImageInspectWithRaw(ctx, ref)
+	}
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return nil, fmt.Errorf("image %s not found", ref)
+		}
+		return nil, fmt.Errorf("failed to inspect image: %w", err)
+	}
+
+	labels := imageLabels(inspect)
+	if labels[LabelManaged] != "true" {
+		return nil, fmt.Errorf("%s is not a claudeway image", ref)
+	}
+	created, _ := time.Parse(time.RFC3339Nano, inspect.Created)
+	info := newImageInfo(inspect.ID, inspect.RepoTags, labels, created, inspect.Size)
+
+	usage, err := imageUsage(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+	info.Containers = usage[inspect.ID]
+	return info, nil
+}
+
+// InspectImage describes a claudeway image, given by tag, ID, or the hash
+// part of its tag
+func InspectImage(ctx context.Context, ref string) (*ImageInfo, error) {
+	cli, err := NewRuntime()
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	return inspectImage(ctx, cli, ref)
+}
+
+// CurrentImageHash returns the hash an image built now would get, without
+// pulling. Missing base images count as unchanged since the current image
+// was built.
+func CurrentImageHash(ctx context.Context) (string, error) {
+	cli, err := NewRuntime()
+	if err != nil {
+		return "", err
+	}
+	defer cli.Close()
+
+	source, err := loadImageSource(ctx, cli)
+	if err != nil {
+		return "", fmt.Errorf("failed to create build context: %w", err)
+	}
+	digests, err := baseDigests(ctx, cli, source.bases)
+	if err != nil {
+		return "", err
+	}
+	known := map[string]string{}
+	if current, _, err := cli.ImageInspectWithRaw(ctx, ImageName); err == nil {
+		known = imageBases(imageLabels(current))
+	}
+	for ref, digest := range digests {
+		known[ref] = digest
+	}
+	return source.hash(known), nil
+}
+
+// RemoveImage removes a claudeway image. Images used by containers are only
+// removed with force, the containers keep running on the removed image.
+func RemoveImage(ctx context.Context, ref string, force bool) (*ImageInfo, error) {
+	cli, err := NewRuntime()
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	info, err := inspectImage(ctx, cli, ref)
+	if err != nil {
+		return nil, err
+	}
+	if info.Containers > 0 && !force {
+		return nil, fmt.Errorf("image %s is used by %d containers, remove them with 'claudeway down' or use --force", ref, info.Containers)
+	}
+
+	// Force removes all tags of the image at once
+	if _, err := cli.ImageRemove(ctx, info.ID, types.ImageRemoveOptions{Force: true, PruneChildren: true}); err != nil {
+		return nil, fmt.Errorf("failed to remove image %s: %w", ref, err)
+	}
+	return info, nil
+}
+
 func init() {
 	// Override the BuildDockerImage function in utils.go
 	BuildDockerImage = func() error {
@@ -0,0 +1,147 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/common-creation/claudeway/internal/config"
+	"github.com/common-creation/claudeway/internal/guest"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// guestSteps converts init steps for the guest agent, dropping comments
+func guestSteps(steps []config.InitStep) []guest.Step {
+	var result []guest.Step
+	for _, step := range steps {
+		if step.IsComment() {
+			continue
+		}
+		result = append(result, guestStep(step))
+	}
+	return result
+}
+
+// timeoutSeconds rounds a step timeout up to whole seconds, so timeouts
+// under a second do not turn into 0, which means no timeout
+func timeoutSeconds(timeout time.Duration) int {
+	return int((timeout + time.Second - 1) / time.Second)
+}
+
+func guestStep(step config.InitStep) guest.Step {
+	result := guest.Step{
+		Name:            step.Label(),
+		Run:             step.Run,
+		AsUser:          !step.RunsAsRoot(),
+		TimeoutSeconds:  timeoutSeconds(step.Timeout),
+		Retries:         step.Retries,
+		ContinueOnError: step.ContinueOnError,
+	}
+	if step.When != nil {
+		result.When = &guest.Condition{
+			FileExists: step.When.FileExists,
+			Env:        step.When.Env,
+			Arch:       step.When.Arch,
+		}
+	}
+	return result
+}
+
+// containerUser returns the container user mapped to the host user, nil if
+// the host user is unknown or root and everything runs as root
+func containerUser() *guest.User {
+	name := os.Getenv("USER")
+	uid := os.Getuid()
+	if name == "" || uid <= 0 {
+		return nil
+	}
+	return &guest.User{
+		Name:   name,
+		UID:    uid,
+		GID:    os.Getgid(),
+		Home:   path.Join("/home", name),
+		Groups: hostGroups(),
+	}
+}
+
+// hostGroups returns the host user's supplementary groups. They are only
+// meaningful on Linux, elsewhere the daemon runs in a VM with its own groups.
+func hostGroups() []guest.Group {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	gids, err := os.Getgroups()
+	if err != nil {
+		return nil
+	}
+
+	var groups []guest.Group
+	for _, gid := range gids {
+		if gid == 0 || gid == os.Getgid() {
+			continue
+		}
+		group := guest.Group{GID: gid}
+		if found, err := user.LookupGroupId(strconv.Itoa(gid)); err == nil {
+			group.Name = found.Name
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// Identity returns how the guest agent mapped the host user to the
+// container, nil if it runs everything as root or has not done it yet
+func (m *Manager) Identity(ctx context.Context) (*guest.Identity, error) {
+	data, err := m.readContainerFile(ctx, guest.IdentityFile)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read identity: %w", err)
+	}
+
+	var identity guest.Identity
+	if err := json.Unmarshal(data, &identity); err != nil {
+		return nil, fmt.Errorf("failed to parse identity: %w", err)
+	}
+	return &identity, nil
+}
+
+// writeGuestSpec writes the spec into a created container before it starts
+func (m *Manager) writeGuestSpec(ctx context.Context, containerID string, spec *guest.Spec) error {
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal guest spec: %w", err)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	dir := strings.TrimPrefix(path.Dir(guest.SpecPath), "/")
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0755}); err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: strings.TrimPrefix(guest.SpecPath, "/"), Mode: 0644, Size: int64(len(data))}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	if err := m.client.CopyToContainer(ctx, containerID, "/", &buf, types.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("failed to write guest spec: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/common-creation/claudeway/internal/config"
+	"github.com/common-creation/claudeway/internal/guest"
+	"github.com/common-creation/claudeway/internal/utils"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+const (
+	// LabelInitImage holds the project path of a derived init image
+	LabelInitImage = "claudeway.init-image"
+	// LabelBaseImage holds the ID of the claudeway image a container's
+	// image is based on
+	LabelBaseImage = "claudeway.base-image"
+
+	initImageRepository = "claudeway-init"
+)
+
+// splitCachedInit returns the leading init steps that can be baked into the
+// init image and the steps left for the guest agent. Only a leading run of
+// steps is cached so the steps still run in their configured order.
+func splitCachedInit(cfg *config.Config) (cached, remaining []config.InitStep) {
+	var steps []config.InitStep
+	for _, step := range cfg.Init {
+		if !step.IsComment() {
+			steps = append(steps, step)
+		}
+	}
+	if cfg.InitCache != config.InitCacheImage {
+		return nil, steps
+	}
+
+	n := 0
+	for n < len(steps) && steps[n].Cacheable() {
+		n++
+	}
+	return steps[:n], steps[n:]
+}
+
+// initDockerfile returns a Dockerfile running each step in its own layer, so
+// changing a step only rebuilds it and the steps after it. Steps that run as
+// user create it in the image first.
+func initDockerfile(steps []config.InitStep, user *guest.User) (string, error) {
+	var dockerfile strings.Builder
+	fmt.Fprintf(&dockerfile, "FROM %s\n", ImageName)
+
+	var userJSON []byte
+	if user != nil {
+		var err error
+		if userJSON, err = json.Marshal(user); err != nil {
+			return "", err
+		}
+	}
+
+	for _, step := range steps {
+		// The guest agent runs the step like it does when the container starts
+		spec := guestStep(step)
+		specJSON, err := json.Marshal(spec)
+		if err != nil {
+			return "", err
+		}
+		command := []string{guest.BinaryPath, "step", string(specJSON)}
+		if spec.AsUser && user != nil {
+			command = append(command, string(userJSON))
+		}
+		args, err := json.Marshal(command)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&dockerfile, "# %s\nRUN %s\n", strings.ReplaceAll(stepLabel(step.Label()), "\n", " "), args)
+	}
+	return dockerfile.String(), nil
+}
+
+// initImage returns the tag of the project's init image for the cached steps,
+// building it if it does not exist yet
+func (m *Manager) initImage(ctx context.Context, steps []config.InitStep) (string, error) {
+	baseID, err := m.imageID(ctx)
+	if err != nil {
+		return "", err
+	}
+	if baseID == "" {
+		return "", fmt.Errorf("image %s not found, run 'claudeway image build'", ImageName)
+	}
+
+	dockerfile, err := initDockerfile(steps, containerUser())
+	if err != nil {
+		return "", fmt.Errorf("failed to create init Dockerfile: %w", err)
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(baseID))
+	hash.Write([]byte("\n"))
+	hash.Write([]byte(dockerfile))
+	tag := fmt.Sprintf("%s:%s-%s", initImageRepository, utils.HashPath(m.workDir), hex.EncodeToString(hash.Sum(nil))[:12])
+
+	if _, _, err := m.client.ImageInspectWithRaw(ctx, tag); err == nil {
+		return tag, nil
+	} else if !client.IsErrNotFound(err) {
+		return "", fmt.Errorf("failed to inspect image: %w", err)
+	}
+
+	fmt.Fprintf(m.out(), "Building init image %s with %d cached steps...\n", tag, len(steps))
+
+	var buildContext bytes.Buffer
+	tw := tar.NewWriter(&buildContext)
+	if err := tw.WriteHeader(&tar.Header{Name: "Dockerfile", Mode: 0644, Size: int64(len(dockerfile))}); err != nil {
+		return "", err
+	}
+	if _, err := tw.Write([]byte(dockerfile)); err != nil {
+		return "", err
+	}
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+
+	resp, err := m.client.ImageBuild(ctx, &buildContext, types.ImageBuildOptions{
+		Dockerfile: "Dockerfile",
+		Tags:       []string{tag},
+		Remove:     true,
+		Labels: map[string]string{
+			LabelManaged:   "true",
+			LabelInitImage: m.workDir,
+			LabelBaseImage: baseID,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to build init image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := readBuildOutput(resp.Body, m.out()); err != nil {
+		return "", fmt.Errorf("failed to build init image: %w", err)
+	}
+
+	m.removeOldInitImages(ctx, tag)
+	return tag, nil
+}
+
+// removeOldInitImages removes the project's previous init images. Images
+// still used by a container are kept.
+func (m *Manager) removeOldInitImages(ctx context.Context, current string) {
+	images, err := m.client.ImageList(ctx, types.ImageListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", LabelInitImage+"="+m.workDir)),
+	})
+	if err != nil {
+		fmt.Fprintf(m.errOut(), "Warning: failed to list old init images: %v\n", err)
+		return
+	}
+
+	for _, image := range images {
+		for _, tag := range image.RepoTags {
+			if tag == current {
+				continue
+			}
+			if _, err := m.client.ImageRemove(ctx, tag, types.ImageRemoveOptions{PruneChildren: true}); err != nil {
+				fmt.Fprintf(m.errOut(), "Warning: failed to remove old init image %s: %v\n", tag, err)
+			}
+		}
+	}
+}
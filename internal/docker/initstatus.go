@@ -0,0 +1,222 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/common-creation/claudeway/internal/guest"
+	"github.com/common-creation/claudeway/internal/term"
+	"github.com/docker/docker/client"
+)
+
+// Lines of a failed step's output shown after initialization failed
+const initFailedLines = 20
+
+// InitStep is the state of a single init step
+type InitStep struct {
+	Number   int           `json:"step"`
+	Name     string        `json:"name"`
+	Running  bool          `json:"running"`
+	ExitCode int           `json:"exit_code"`
+	Duration time.Duration `json:"duration"`
+	// Ignored is set when a step with continue_on_error failed
+	Ignored bool `json:"ignored,omitempty"`
+	// Skipped is set when the step's condition did not match
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// InitProgress is the initialization state of the container's current run
+type InitProgress struct {
+	// Started is false until the guest agent of the current run wrote its
+	// first event
+	Started bool `json:"started"`
+	// UserReady is set once the container user exists, sessions may start
+	// before the remaining steps finished
+	UserReady  bool       `json:"user_ready"`
+	Total      int        `json:"total"`
+	Steps      []InitStep `json:"steps"`
+	Skipped    bool       `json:"skipped"`
+	Complete   bool       `json:"complete"`
+	Failed     bool       `json:"failed"`
+	FailedStep int        `json:"failed_step,omitempty"`
+}
+
+// InitProgress returns the initialization state of the container's current
+// run. It also works for a stopped container, describing its last run.
+func (m *Manager) InitProgress(ctx context.Context) (*InitProgress, error) {
+	inspect, err := m.client.ContainerInspect(ctx, m.containerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	startedAt, _ := time.Parse(time.RFC3339Nano, inspect.State.StartedAt)
+	return m.initProgress(ctx, startedAt)
+}
+
+func (m *Manager) initProgress(ctx context.Context, startedAt time.Time) (*InitProgress, error) {
+	progress := &InitProgress{}
+
+	data, err := m.readContainerFile(ctx, guest.StatusFile)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return progress, nil
+		}
+		return nil, fmt.Errorf("failed to read init status: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var event guest.Event
+		// The last line may still be incomplete
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			break
+		}
+
+		switch event.Event {
+		case "begin":
+			// A restarted container still has the file of its previous
+			// run until the guest agent replaced it. The daemon records the
+			// start time slightly after the process was started.
+			if event.Time < startedAt.Add(-500*time.Millisecond).UnixMilli() {
+				return &InitProgress{}, nil
+			}
+			progress.Started = true
+		case "user_ready":
+			progress.UserReady = true
+		case "start":
+			progress.Total = event.Total
+			progress.Steps = append(progress.Steps, InitStep{
+				Number:  event.Step,
+				Name:    event.Name,
+				Running: true,
+			})
+		case "end":
+			if n := len(progress.Steps); n > 0 && progress.Steps[n-1].Number == event.Step {
+				progress.Steps[n-1].Running = false
+				progress.Steps[n-1].ExitCode = event.ExitCode
+				progress.Steps[n-1].Duration = time.Duration(event.DurationMs) * time.Millisecond
+				progress.Steps[n-1].Ignored = event.Ignored
+				progress.Steps[n-1].Skipped = event.Skipped
+			}
+		case "skipped":
+			progress.Skipped = true
+		case "complete":
+			progress.Complete = true
+		case "failed":
+			progress.Failed = true
+			progress.FailedStep = event.Step
+		}
+	}
+
+	if !progress.Started {
+		return &InitProgress{}, nil
+	}
+	return progress, nil
+}
+
+// initStepOutput returns the last lines of an init step's output
+func (m *Manager) initStepOutput(ctx context.Context, step int, lines int) ([]string, error) {
+	data, err := m.readContainerFile(ctx, fmt.Sprintf("%s/steps/%d.log", guest.StatusDir, step))
+	if err != nil {
+		return nil, err
+	}
+
+	output := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(output) > lines {
+		output = output[len(output)-lines:]
+	}
+	return output, nil
+}
+
+// readContainerFile reads a file from the container. Unlike an exec this
+// also works when the container has stopped.
+func (m *Manager) readContainerFile(ctx context.Context, path string) ([]byte, error) {
+	reader, _, err := m.client.CopyFromContainer(ctx, m.containerName, path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	tr := tar.NewReader(reader)
+	if _, err := tr.Next(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return io.ReadAll(tr)
+}
+
+// initRenderer prints the steps of an InitProgress as they start and end.
+// On a terminal the line of a running step is replaced once it ended.
+type initRenderer struct {
+	out     io.Writer
+	tty     bool
+	printed int
+	active  int
+}
+
+func newInitRenderer(out io.Writer) *initRenderer {
+	tty := false
+	if f, ok := out.(*os.File); ok {
+		tty = term.IsTerminal(f.Fd())
+	}
+	return &initRenderer{out: out, tty: tty}
+}
+
+func (r *initRenderer) render(progress *InitProgress) {
+	for r.printed < len(progress.Steps) {
+		step := progress.Steps[r.printed]
+		label := fmt.Sprintf("[%d/%d] %s", step.Number, progress.Total, stepLabel(step.Name))
+
+		if step.Running {
+			if r.active != step.Number {
+				if r.tty {
+					fmt.Fprintf(r.out, "  %s ...", label)
+				} else {
+					fmt.Fprintf(r.out, "  %s ...\n", label)
+				}
+				r.active = step.Number
+			}
+			return
+		}
+
+		if r.tty && r.active == step.Number {
+			fmt.Fprint(r.out, "\r\033[K")
+		}
+		duration := step.Duration.Round(100 * time.Millisecond)
+		switch {
+		case step.Skipped:
+			fmt.Fprintf(r.out, "  - %s (skipped)\n", label)
+		case step.ExitCode == 0:
+			fmt.Fprintf(r.out, "  ✓ %s (%s)\n", label, duration)
+		case step.Ignored:
+			fmt.Fprintf(r.out, "  ✗ %s (exit code %d, ignored, %s)\n", label, step.ExitCode, duration)
+		default:
+			fmt.Fprintf(r.out, "  ✗ %s (exit code %d, %s)\n", label, step.ExitCode, duration)
+		}
+		r.active = 0
+		r.printed++
+	}
+}
+
+// Label returns the step's name shortened to a single line
+func (s InitStep) Label() string {
+	return stepLabel(s.Name)
+}
+
+// stepLabel shortens a step name to a single line
+func stepLabel(name string) string {
+	if i := strings.IndexByte(name, '\n'); i >= 0 {
+		name = name[:i] + " ..."
+	}
+	if len(name) > 72 {
+		name = name[:69] + "..."
+	}
+	return name
+}
@@ -0,0 +1,137 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/common-creation/claudeway/internal/config"
+	"github.com/common-creation/claudeway/internal/utils"
+)
+
+// LogsOptions controls which container logs are streamed
+type LogsOptions struct {
+	Follow bool
+	Since  string
+}
+
+// StreamLogs copies the container logs to w. The container runs with a TTY,
+// so stdout and stderr arrive as a single stream.
+func (m *Manager) StreamLogs(ctx context.Context, options LogsOptions, w io.Writer) error {
+	reader, err := m.client.ContainerLogs(ctx, m.containerName, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     options.Follow,
+		Since:      options.Since,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get container logs: %w", err)
+	}
+	defer reader.Close()
+
+	if _, err := io.Copy(w, reader); err != nil {
+		return fmt.Errorf("failed to read container logs: %w", err)
+	}
+	return nil
+}
+
+// InitLogDir returns the directory holding the persisted init logs of this
+// project's containers, including throwaway ones
+func (m *Manager) InitLogDir() string {
+	project := fmt.Sprintf("claudeway-%s", utils.HashPath(m.workDir))
+	return filepath.Join(config.GetStateDir(), "claudeway", "logs", project)
+}
+
+// InitLogs returns the paths of the persisted init logs, oldest first
+func (m *Manager) InitLogs() ([]string, error) {
+	entries, err := os.ReadDir(m.InitLogDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), "init-") && strings.HasSuffix(entry.Name(), ".log") {
+			names = append(names, entry.Name())
+		}
+	}
+
+	// File names contain a sortable timestamp and a counter for logs
+	// started in the same millisecond
+	sort.Slice(names, func(i, j int) bool {
+		stampI, counterI := initLogKey(names[i])
+		stampJ, counterJ := initLogKey(names[j])
+		if stampI != stampJ {
+			return stampI < stampJ
+		}
+		return counterI < counterJ
+	})
+
+	logs := make([]string, len(names))
+	for i, name := range names {
+		logs[i] = filepath.Join(m.InitLogDir(), name)
+	}
+	return logs, nil
+}
+
+// initLogStamp is the timestamp format of init log names
+const initLogStamp = "20060102-150405.000"
+
+// maxInitLogs is how many init logs are kept per project
+const maxInitLogs = 20
+
+// initLogKey splits the name of an init log into its timestamp and counter
+func initLogKey(name string) (string, int) {
+	base := strings.TrimSuffix(strings.TrimPrefix(name, "init-"), ".log")
+	if len(base) > len(initLogStamp) && base[len(initLogStamp)] == '-' {
+		if counter, err := strconv.Atoi(base[len(initLogStamp)+1:]); err == nil {
+			return base[:len(initLogStamp)], counter
+		}
+	}
+	return base, 0
+}
+
+func (m *Manager) createInitLog() (*os.File, error) {
+	if err := os.MkdirAll(m.InitLogDir(), 0755); err != nil {
+		return nil, err
+	}
+
+	// Never overwrite the log of another init started at the same time
+	stamp := time.Now().Format(initLogStamp)
+	var file *os.File
+	for i := 0; file == nil; i++ {
+		name := fmt.Sprintf("init-%s-%d.log", stamp, i)
+		var err error
+		file, err = os.OpenFile(filepath.Join(m.InitLogDir(), name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil && !os.IsExist(err) {
+			return nil, err
+		}
+	}
+	m.removeOldInitLogs()
+
+	fmt.Fprintf(file, "[claudeway] container %s, project %s\n", m.containerName, m.workDir)
+	return file, nil
+}
+
+// removeOldInitLogs keeps only the newest maxInitLogs init logs
+func (m *Manager) removeOldInitLogs() {
+	logs, err := m.InitLogs()
+	if err != nil || len(logs) <= maxInitLogs {
+		return
+	}
+	for _, log := range logs[:len(logs)-maxInitLogs] {
+		if err := os.Remove(log); err != nil {
+			fmt.Fprintf(m.errOut(), "Warning: failed to remove old init log %s: %v\n", log, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package docker_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/common-creation/claudeway/internal/config"
+)
+
+func TestInitLogsOrder(t *testing.T) {
+	_, manager := newManager(t)
+	dir := manager.InitLogDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{
+		"init-20260102-100000.000-10.log",
+		"init-20260102-100000.000-2.log",
+		"init-20260101-235959.999-0.log",
+		"init-20260102-100000.000-0.log",
+		"other.log",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logs, err := manager.InitLogs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, log := range logs {
+		got = append(got, filepath.Base(log))
+	}
+	want := []string{
+		"init-20260101-235959.999-0.log",
+		"init-20260102-100000.000-0.log",
+		"init-20260102-100000.000-2.log",
+		"init-20260102-100000.000-10.log",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("InitLogs() = %q, want %q", got, want)
+	}
+}
+
+func TestInitLogsAreCapped(t *testing.T) {
+	_, manager := newManager(t)
+	dir := manager.InitLogDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 25; i++ {
+		name := fmt.Sprintf("init-20200101-0000%02d.000-0.log", i)
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	if err := manager.CreateAndStartContainer(ctx, &config.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.WaitForInitialization(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	logs, err := manager.InitLogs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 20 {
+		t.Fatalf("%d init logs kept, want 20", len(logs))
+	}
+	if strings.HasPrefix(filepath.Base(logs[0]), "init-20200101-000000") {
+		t.Error("the oldest log was kept")
+	}
+	if data, _ := os.ReadFile(logs[len(logs)-1]); !strings.HasPrefix(string(data), "[claudeway] container") {
+		t.Error("the newest log is not the one just written")
+	}
+}
@@ -0,0 +1,42 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MapsHostUser reports whether commands run as a container user mapped to
+// the host user. Otherwise everything runs as root.
+func MapsHostUser() bool {
+	return containerUser() != nil
+}
+
+// FixOwnership hands paths in the workspace back to the host user. The host
+// user cannot change the owner of files owned by root, so chown runs as root
+// in the container. The paths are passed through xargs, there may be more
+// than fit on one command line.
+func (m *Manager) FixOwnership(ctx context.Context, paths []string) error {
+	user := containerUser()
+	if user == nil {
+		return fmt.Errorf("the host user is not mapped into the container")
+	}
+
+	owner := fmt.Sprintf("%d:%d", user.UID, user.GID)
+	var output bytes.Buffer
+	exitCode, err := m.Exec(ctx, ExecOptions{
+		Cmd:    []string{"xargs", "-0", "chown", "-R", "-h", owner, "--"},
+		User:   "root",
+		Stdin:  strings.NewReader(strings.Join(paths, "\x00")),
+		Stdout: &output,
+		Stderr: &output,
+	})
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("chown exited with code %d: %s", exitCode, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
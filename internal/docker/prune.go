@@ -0,0 +1,263 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// PruneOptions controls which sandboxes are considered stale
+type PruneOptions struct {
+	// IdleAfter is how long a stopped sandbox may stay unused before it is
+	// pruned. Zero disables the idle check.
+	IdleAfter time.Duration
+}
+
+// PruneContainer is a sandbox container selected for removal
+type PruneContainer struct {
+	ID      string
+	Name    string
+	Project string
+	Reason  string
+}
+
+// PruneImage is a claudeway image selected for removal
+type PruneImage struct {
+	ID      string
+	Created time.Time
+	Size    int64
+}
+
+// PruneVolume is a claudeway volume selected for removal
+type PruneVolume struct {
+	Name    string
+	Project string
+}
+
+// PruneCandidates lists everything prune would remove
+type PruneCandidates struct {
+	Containers []PruneContainer
+	Images     []PruneImage
+	Volumes    []PruneVolume
+	// Workspaces are the synced workspace volumes of projects that no
+	// longer exist. They may hold changes that were never synced back.
+	Workspaces []PruneVolume
+}
+
+// Empty reports whether there is nothing to prune
+func (c *PruneCandidates) Empty() bool {
+	return len(c.Containers) == 0 && len(c.Images) == 0 && len(c.Volumes) == 0 && len(c.Workspaces) == 0
+}
+
+// FindPruneCandidates finds sandboxes whose project directory no longer exists
+// or that have been idle for too long, plus dangling claudeway images and
+// volumes, earlier builds of the claudeway image and the init images and
+// synced workspace volumes of projects that no longer exist. Running
+// sandboxes are never selected.
+func FindPruneCandidates(ctx context.Context, options PruneOptions) (*PruneCandidates, error) {
+	cli, err := NewRuntime()
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	candidates := &PruneCandidates{}
+
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("name", "claudeway-")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	// Images of the containers that are kept
+	usedImages := make(map[string]bool)
+	for _, c := range containers {
+		if c.State == "running" || !hasSandboxName(c.Names) {
+			usedImages[c.ImageID] = true
+			continue
+		}
+
+		project := c.Labels[LabelProject]
+		reason := ""
+		if project != "" {
+			if _, err := os.Stat(project); os.IsNotExist(err) {
+				reason = "project directory no longer exists"
+			}
+		}
+
+		if reason == "" && options.IdleAfter > 0 {
+			inspect, err := cli.ContainerInspect(ctx, c.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to inspect container %s: %w", c.ID, err)
+			}
+			lastUsed, err := time.Parse(time.RFC3339Nano, inspect.State.FinishedAt)
+			if err != nil || lastUsed.IsZero() || lastUsed.Year() < 2000 {
+				// Never started, fall back to the creation time
+				lastUsed = time.Unix(c.Created, 0)
+			}
+			if idle := time.Since(lastUsed); idle > options.IdleAfter {
+				reason = fmt.Sprintf("idle for %s", idle.Round(time.Hour))
+			}
+		}
+
+		if reason == "" {
+			usedImages[c.ImageID] = true
+		} else {
+			candidates.Containers = append(candidates.Containers, PruneContainer{
+				ID:      c.ID,
+				Name:    strings.TrimPrefix(c.Names[0], "/"),
+				Project: project,
+				Reason:  reason,
+			})
+		}
+	}
+
+	images, err := cli.ImageList(ctx, types.ImageListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("dangling", "true"),
+			filters.Arg("label", LabelManaged+"=true"),
+		),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	for _, image := range images {
+		// Old builds may still be the image of a kept container
+		if usedImages[image.ID] {
+			continue
+		}
+		candidates.Images = append(candidates.Images, PruneImage{
+			ID:      image.ID,
+			Created: time.Unix(image.Created, 0),
+			Size:    image.Size,
+		})
+	}
+
+	// Init images of projects that no longer exist
+	initImages, err := cli.ImageList(ctx, types.ImageListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", LabelInitImage)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	for _, image := range initImages {
+		if usedImages[image.ID] {
+			// The build an init image is based on cannot be removed before it
+			usedImages[image.Labels[LabelBaseImage]] = true
+			continue
+		}
+		if _, err := os.Stat(image.Labels[LabelInitImage]); !os.IsNotExist(err) {
+			usedImages[image.Labels[LabelBaseImage]] = true
+			continue
+		}
+		candidates.Images = append(candidates.Images, PruneImage{
+			ID:      image.ID,
+			Created: time.Unix(image.Created, 0),
+			Size:    image.Size,
+		})
+	}
+
+	// Earlier builds keep their content-addressed tags when a new build
+	// becomes claudeway:latest
+	builds, err := cli.ImageList(ctx, types.ImageListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("dangling", "false"),
+			filters.Arg("label", LabelImageHash),
+		),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	for _, image := range builds {
+		if usedImages[image.ID] || containsString(image.RepoTags, ImageName) {
+			continue
+		}
+		candidates.Images = append(candidates.Images, PruneImage{
+			ID:      image.ID,
+			Created: time.Unix(image.Created, 0),
+			Size:    image.Size,
+		})
+	}
+
+	volumes, err := cli.VolumeList(ctx, filters.NewArgs(
+		filters.Arg("dangling", "true"),
+		filters.Arg("label", LabelManaged+"=true"),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+	for _, volume := range volumes.Volumes {
+		// Workspace volumes outlive their containers on purpose
+		if volume.Labels[LabelWorkspace] != "" {
+			project := volume.Labels[LabelProject]
+			if _, err := os.Stat(project); project != "" && os.IsNotExist(err) {
+				candidates.Workspaces = append(candidates.Workspaces, PruneVolume{Name: volume.Name, Project: project})
+			}
+			continue
+		}
+		candidates.Volumes = append(candidates.Volumes, PruneVolume{Name: volume.Name})
+	}
+
+	return candidates, nil
+}
+
+// Prune removes the given candidates. Containers go first so that their
+// images and volumes are no longer in use.
+func Prune(ctx context.Context, candidates *PruneCandidates) error {
+	cli, err := NewRuntime()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	for _, c := range candidates.Containers {
+		if err := cli.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{RemoveVolumes: true}); err != nil {
+			return fmt.Errorf("failed to remove container %s: %w", c.Name, err)
+		}
+		fmt.Printf("Removed container %s\n", c.Name)
+	}
+
+	for _, image := range candidates.Images {
+		// Init images may carry more than one tag, none of the images is
+		// used by a container that is kept
+		if _, err := cli.ImageRemove(ctx, image.ID, types.ImageRemoveOptions{Force: true, PruneChildren: true}); err != nil {
+			return fmt.Errorf("failed to remove image %s: %w", ShortID(image.ID), err)
+		}
+		fmt.Printf("Removed image %s\n", ShortID(image.ID))
+	}
+
+	for _, volume := range candidates.Volumes {
+		if err := cli.VolumeRemove(ctx, volume.Name, false); err != nil {
+			return fmt.Errorf("failed to remove volume %s: %w", volume.Name, err)
+		}
+		fmt.Printf("Removed volume %s\n", volume.Name)
+	}
+
+	for _, volume := range candidates.Workspaces {
+		if err := cli.VolumeRemove(ctx, volume.Name, false); err != nil {
+			return fmt.Errorf("failed to remove volume %s: %w", volume.Name, err)
+		}
+		os.Remove(syncStateFile(strings.TrimSuffix(volume.Name, "-workspace")))
+		fmt.Printf("Removed workspace volume %s\n", volume.Name)
+	}
+
+	return nil
+}
+
+// hasSandboxName reports whether a container is named like a claudeway
+// sandbox, the name filter of the list API also matches substrings
+func hasSandboxName(names []string) bool {
+	for _, name := range names {
+		if strings.HasPrefix(name, "/claudeway-") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/common-creation/claudeway/internal/guest"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// Init states reported by Status
+const (
+	InitComplete   = "complete"
+	InitInProgress = "in progress"
+	InitFailed     = "failed"
+	InitUnknown    = "unknown"
+)
+
+// Status describes the state of the project's container
+type Status struct {
+	Name        string          `json:"name"`
+	Project     string          `json:"project"`
+	Runtime     string          `json:"runtime,omitempty"`
+	Endpoint    string          `json:"endpoint,omitempty"`
+	Workspace   string          `json:"workspace,omitempty"`
+	Exists      bool            `json:"exists"`
+	State       string          `json:"state,omitempty"`
+	Running     bool            `json:"running"`
+	StartedAt   *time.Time      `json:"started_at,omitempty"`
+	Init        string          `json:"init,omitempty"`
+	InitSteps   []InitStep      `json:"init_steps,omitempty"`
+	User        *guest.Identity `json:"user,omitempty"`
+	ExitCode    int             `json:"exit_code"`
+	OOMKilled   bool            `json:"oom_killed"`
+	NetworkMode string          `json:"network_mode,omitempty"`
+	Mounts      []MountStatus   `json:"mounts,omitempty"`
+	Resources   *ResourceUsage  `json:"resources,omitempty"`
+}
+
+// MountStatus describes a single mount of the container
+type MountStatus struct {
+	Type     string `json:"type"`
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+// ResourceUsage is a single sample of the container's resource usage
+type ResourceUsage struct {
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryUsage   uint64  `json:"memory_usage"`
+	MemoryLimit   uint64  `json:"memory_limit"`
+	MemoryPercent float64 `json:"memory_percent"`
+	NetworkRx     uint64  `json:"network_rx"`
+	NetworkTx     uint64  `json:"network_tx"`
+	BlockRead     uint64  `json:"block_read"`
+	BlockWrite    uint64  `json:"block_write"`
+	PIDs          uint64  `json:"pids"`
+}
+
+// Status inspects the container and, if it is running, samples its resource
+// usage from the stats API
+func (m *Manager) Status(ctx context.Context) (*Status, error) {
+	status := &Status{
+		Name:    m.containerName,
+		Project: m.workDir,
+	}
+
+	engine, err := m.Engine(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status.Runtime = engine.String()
+	if endpoint, err := ResolveEndpoint(); err == nil {
+		status.Endpoint = endpoint.String()
+	}
+
+	inspect, err := m.client.ContainerInspect(ctx, m.containerName)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return status, nil
+		}
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	status.Exists = true
+	status.State = inspect.State.Status
+	status.Running = inspect.State.Running
+	status.ExitCode = inspect.State.ExitCode
+	status.OOMKilled = inspect.State.OOMKilled
+	if inspect.Config != nil {
+		status.Workspace = inspect.Config.Labels[LabelWorkspace]
+	}
+	if inspect.HostConfig != nil {
+		status.NetworkMode = string(inspect.HostConfig.NetworkMode)
+	}
+	// Containers that never started report the zero time
+	if startedAt, err := time.Parse(time.RFC3339Nano, inspect.State.StartedAt); err == nil && !startedAt.IsZero() {
+		status.StartedAt = &startedAt
+	}
+
+	for _, mount := range inspect.Mounts {
+		status.Mounts = append(status.Mounts, MountStatus{
+			Type:     string(mount.Type),
+			Source:   mount.Source,
+			Target:   mount.Destination,
+			ReadOnly: !mount.RW,
+		})
+	}
+
+	status.Init, err = m.initState(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+
+	status.User, err = m.Identity(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if status.Running {
+		status.Resources, err = m.resourceUsage(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+func (m *Manager) initState(ctx context.Context, status *Status) (string, error) {
+	progress, err := m.InitProgress(ctx)
+	if err != nil {
+		return "", err
+	}
+	status.InitSteps = progress.Steps
+
+	switch {
+	case progress.Complete:
+		return InitComplete, nil
+	case progress.Failed:
+		return InitFailed, nil
+	case status.Running:
+		return InitInProgress, nil
+	case status.ExitCode != 0:
+		// The guest agent exits with an error only when initialization fails
+		return InitFailed, nil
+	}
+	return InitUnknown, nil
+}
+
+func (m *Manager) resourceUsage(ctx context.Context) (*ResourceUsage, error) {
+	// Without streaming, the daemon takes two samples so CPU usage can be computed
+	resp, err := m.client.ContainerStats(ctx, m.containerName, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var stats types.StatsJSON
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("failed to decode container stats: %w", err)
+	}
+
+	usage := &ResourceUsage{
+		CPUPercent:  cpuPercent(&stats),
+		MemoryUsage: memoryUsage(&stats),
+		MemoryLimit: stats.MemoryStats.Limit,
+		PIDs:        stats.PidsStats.Current,
+	}
+	if usage.MemoryLimit > 0 {
+		usage.MemoryPercent = float64(usage.MemoryUsage) / float64(usage.MemoryLimit) * 100
+	}
+
+	for _, network := range stats.Networks {
+		usage.NetworkRx += network.RxBytes
+		usage.NetworkTx += network.TxBytes
+	}
+
+	for _, entry := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			usage.BlockRead += entry.Value
+		case "write":
+			usage.BlockWrite += entry.Value
+		}
+	}
+
+	return usage, nil
+}
+
+// cpuPercent computes CPU usage the same way 'docker stats' does
+func cpuPercent(stats *types.StatsJSON) float64 {
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+
+	onlineCPUs := float64(stats.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+	return cpuDelta / systemDelta * onlineCPUs * 100
+}
+
+// memoryUsage excludes the page cache, matching 'docker stats'
+func memoryUsage(stats *types.StatsJSON) uint64 {
+	cache := stats.MemoryStats.Stats["inactive_file"] // cgroup v2
+	if v, ok := stats.MemoryStats.Stats["total_inactive_file"]; ok {
+		cache = v // cgroup v1
+	}
+	if cache > stats.MemoryStats.Usage {
+		return stats.MemoryStats.Usage
+	}
+	return stats.MemoryStats.Usage - cache
+}
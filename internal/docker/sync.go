@@ -0,0 +1,691 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/common-creation/claudeway/internal/config"
+	"github.com/common-creation/claudeway/internal/workspace"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/mount"
+	volumetypes "github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+)
+
+// LabelWorkspace holds how the workspace gets into the container, bind or
+// sync
+const LabelWorkspace = "claudeway.workspace"
+
+// syncInterval is how often a synced workspace is synced during sessions
+const syncInterval = 2 * time.Second
+
+// syncsWorkspace reports whether a new container syncs the workspace
+// instead of bind-mounting it
+func syncsWorkspace(cfg *config.Config) (bool, error) {
+	switch cfg.WorkspaceMode() {
+	case config.WorkspaceSync:
+		return true, nil
+	case config.WorkspaceBind:
+		return false, nil
+	}
+	endpoint, err := ResolveEndpoint()
+	if err != nil {
+		return false, err
+	}
+	return endpoint.Remote(), nil
+}
+
+// SyncsWorkspace reports whether the container has a synced workspace
+func (m *Manager) SyncsWorkspace(ctx context.Context) (bool, error) {
+	inspect, err := m.client.ContainerInspect(ctx, m.containerName)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	return inspect.Config != nil && inspect.Config.Labels[LabelWorkspace] == config.WorkspaceSync, nil
+}
+
+// workspaceVolume returns the name of the volume holding a synced workspace
+func (m *Manager) workspaceVolume() string {
+	return m.containerName + "-workspace"
+}
+
+func (m *Manager) syncStateFile() string {
+	return syncStateFile(m.containerName)
+}
+
+// syncStateFile returns where the sync state of a container's workspace is
+// kept
+func syncStateFile(containerName string) string {
+	return filepath.Join(config.GetStateDir(), "claudeway", "sync", containerName+".json")
+}
+
+// workspaceMount creates the workspace volume if needed and returns its
+// mount. fresh is set if the volume was created.
+func (m *Manager) workspaceMount(ctx context.Context) (mnt mount.Mount, fresh bool, err error) {
+	name := m.workspaceVolume()
+	if _, err := m.client.VolumeInspect(ctx, name); err != nil {
+		if !client.IsErrNotFound(err) {
+			return mnt, false, fmt.Errorf("failed to inspect workspace volume %s: %w", name, err)
+		}
+		_, err := m.client.VolumeCreate(ctx, volumetypes.VolumeCreateBody{
+			Name: name,
+			// The workspace label keeps the volume out of the sweep of
+			// dangling volumes, it may hold changes that were not synced
+			Labels: map[string]string{
+				LabelManaged:   "true",
+				LabelProject:   m.workDir,
+				LabelWorkspace: config.WorkspaceSync,
+			},
+		})
+		if err != nil {
+			return mnt, false, fmt.Errorf("failed to create workspace volume %s: %w", name, err)
+		}
+		fresh = true
+	}
+
+	return mount.Mount{
+		Type:   mount.TypeVolume,
+		Source: name,
+		Target: m.workDir,
+	}, fresh, nil
+}
+
+// seedWorkspace pushes what changed locally since the last sync into a
+// created container, before its init steps need the workspace. Deletions
+// and remote changes need a running container, the first sync of a session
+// takes care of them.
+func (m *Manager) seedWorkspace(ctx context.Context, containerID string, exclude []string, fresh bool) error {
+	state := &workspace.SyncState{Local: workspace.Tree{}, Remote: workspace.Tree{}}
+	if !fresh {
+		var err error
+		if state, err = workspace.LoadSyncState(m.syncStateFile()); err != nil {
+			return fmt.Errorf("failed to read sync state: %w", err)
+		}
+	}
+	state.Exclude = exclude
+
+	local, err := workspace.ScanLocal(m.workDir, exclude)
+	if err != nil {
+		return fmt.Errorf("failed to scan the workspace: %w", err)
+	}
+	plan := workspace.PlanSync(state, local, state.Remote)
+
+	fmt.Fprintf(m.out(), "Copying %d paths of the workspace into the container...\n", len(plan.Push))
+	if err := m.pushPaths(ctx, containerID, plan.Push); err != nil {
+		return err
+	}
+
+	// Pending deletions stay in the last local state, so the next sync
+	// still sees them as deleted
+	newLocal := workspace.Tree{}
+	for p, entry := range local {
+		newLocal[p] = entry
+	}
+	for p, entry := range state.Local {
+		if _, ok := local[p]; !ok {
+			newLocal[p] = entry
+		}
+	}
+	for _, p := range plan.Push {
+		state.Remote[p] = local[p]
+	}
+	state.Local = newLocal
+	return state.Save(m.syncStateFile())
+}
+
+// SyncWorkspace syncs the changes since the last sync in both directions.
+// The container must be running.
+func (m *Manager) SyncWorkspace(ctx context.Context) (*workspace.SyncPlan, error) {
+	state, err := workspace.LoadSyncState(m.syncStateFile())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync state: %w", err)
+	}
+
+	local, err := workspace.ScanLocal(m.workDir, state.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan the workspace: %w", err)
+	}
+	remote, err := m.remoteTree(ctx, state.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := workspace.PlanSync(state, local, remote)
+	if !plan.Empty() {
+		if err := m.deleteRemote(ctx, plan.DeleteRemote); err != nil {
+			return nil, err
+		}
+		for _, p := range plan.DeleteLocal {
+			if err := os.RemoveAll(filepath.Join(m.workDir, filepath.FromSlash(p))); err != nil {
+				return nil, fmt.Errorf("failed to delete %s: %w", p, err)
+			}
+		}
+		if err := m.pushPaths(ctx, m.containerName, plan.Push); err != nil {
+			return nil, err
+		}
+		if err := m.pullPaths(ctx, plan.Pull); err != nil {
+			return nil, err
+		}
+
+		// Record the state after the transfers, which set the times
+		if local, err = workspace.ScanLocal(m.workDir, state.Exclude); err != nil {
+			return nil, fmt.Errorf("failed to scan the workspace: %w", err)
+		}
+		if remote, err = m.remoteTree(ctx, state.Exclude); err != nil {
+			return nil, err
+		}
+	}
+
+	state.Local, state.Remote = local, remote
+	if err := state.Save(m.syncStateFile()); err != nil {
+		return nil, fmt.Errorf("failed to save sync state: %w", err)
+	}
+	return plan, nil
+}
+
+// SyncSummary adds up the syncs of a session
+type SyncSummary struct {
+	Pushed    int
+	Pulled    int
+	Deleted   int
+	Conflicts []string
+}
+
+// Add counts the paths of a sync
+func (s *SyncSummary) Add(plan *workspace.SyncPlan) {
+	s.Pushed += len(plan.Push)
+	s.Pulled += len(plan.Pull)
+	s.Deleted += len(plan.DeleteRemote) + len(plan.DeleteLocal)
+	s.Conflicts = append(s.Conflicts, plan.Conflicts...)
+}
+
+// WatchWorkspace syncs a synced workspace every few seconds until ctx is
+// done, then syncs a last time. Errors of single syncs are retried, only
+// the last one is returned.
+func (m *Manager) WatchWorkspace(ctx context.Context) (*SyncSummary, error) {
+	summary := &SyncSummary{}
+	var lastErr error
+	syncOnce := func(ctx context.Context) {
+		plan, err := m.SyncWorkspace(ctx)
+		lastErr = err
+		if err == nil {
+			summary.Add(plan)
+		}
+	}
+
+	syncOnce(ctx)
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			syncOnce(ctx)
+		case <-ctx.Done():
+			// The session is over, but its last changes still count
+			syncOnce(context.Background())
+			return summary, lastErr
+		}
+	}
+}
+
+// startWorkspaceSync keeps a synced workspace in sync while a session runs.
+// The returned function stops it after a last sync and reports the result.
+func (m *Manager) startWorkspaceSync(ctx context.Context) (stop func(), err error) {
+	synced, err := m.SyncsWorkspace(ctx)
+	if err != nil || !synced {
+		return func() {}, err
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	var summary *SyncSummary
+	var watchErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		summary, watchErr = m.WatchWorkspace(watchCtx)
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+		summary.Print(m.errOut())
+		if watchErr != nil {
+			fmt.Fprintf(m.errOut(), "Warning: failed to sync the workspace: %v\n", watchErr)
+		}
+	}, nil
+}
+
+// Print describes the summary, nothing if no path was synced
+func (s *SyncSummary) Print(w io.Writer) {
+	if s.Pushed+s.Pulled+s.Deleted == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Synced workspace: %d to the container, %d back, %d deleted\n", s.Pushed, s.Pulled, s.Deleted)
+	for _, p := range s.Conflicts {
+		fmt.Fprintf(w, "  Changed on both sides, kept the newer version: %s\n", p)
+	}
+}
+
+// syncWorkspaceBeforeStop syncs back a synced workspace before its container
+// stops. It reports whether the workspace is synced.
+func (m *Manager) syncWorkspaceBeforeStop(ctx context.Context) (bool, error) {
+	synced, err := m.SyncsWorkspace(ctx)
+	if err != nil || !synced {
+		return false, err
+	}
+	if running, err := m.IsContainerRunning(ctx); err != nil || !running {
+		return true, err
+	}
+	plan, err := m.SyncWorkspace(ctx)
+	if err != nil {
+		return true, err
+	}
+	summary := &SyncSummary{}
+	summary.Add(plan)
+	summary.Print(m.errOut())
+	return true, nil
+}
+
+// remoteTree lists the workspace in the container
+func (m *Manager) remoteTree(ctx context.Context, exclude []string) (workspace.Tree, error) {
+	cmd := []string{"find", ".", "-mindepth", "1"}
+	if len(exclude) > 0 {
+		cmd = append(cmd, "(")
+		for i, pattern := range exclude {
+			if i > 0 {
+				cmd = append(cmd, "-o")
+			}
+			cmd = append(cmd, "-name", pattern)
+		}
+		cmd = append(cmd, ")", "-prune", "-o")
+	}
+	cmd = append(cmd, "-printf", `%y\0%s\0%T@\0%m\0%P\0%l\0`)
+
+	var stdout, stderr bytes.Buffer
+	exitCode, err := m.Exec(ctx, ExecOptions{
+		Cmd:        cmd,
+		User:       "root",
+		WorkingDir: m.workDir,
+		Stdout:     &stdout,
+		Stderr:     &stderr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the workspace in the container: %w", err)
+	}
+	if exitCode != 0 {
+		return nil, fmt.Errorf("failed to list the workspace in the container: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	tree := workspace.Tree{}
+	fields := strings.Split(stdout.String(), "\x00")
+	for i := 0; i+6 <= len(fields); i += 6 {
+		kind, size, mtime, mode, name, link := fields[i], fields[i+1], fields[i+2], fields[i+3], fields[i+4], fields[i+5]
+		if workspace.Excluded(name, exclude) {
+			continue
+		}
+		entry := workspace.Entry{Type: kind}
+		if perm, err := strconv.ParseUint(mode, 8, 32); err == nil {
+			entry.Mode = os.FileMode(perm)
+		}
+		switch kind {
+		case workspace.EntryFile:
+			entry.Size, _ = strconv.ParseInt(size, 10, 64)
+			seconds, _ := strconv.ParseFloat(mtime, 64)
+			entry.MTime = int64(math.Floor(seconds))
+		case workspace.EntryDir:
+		case workspace.EntrySymlink:
+			entry.Link = link
+		default:
+			continue
+		}
+		tree[name] = entry
+	}
+	return tree, nil
+}
+
+// pushPaths copies paths of the local workspace into the container, owned
+// by the container user
+func (m *Manager) pushPaths(ctx context.Context, containerID string, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	uid, gid := 0, 0
+	if user := containerUser(); user != nil {
+		uid, gid = user.UID, user.GID
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeWorkspaceTar(writer, m.workDir, paths, uid, gid))
+	}()
+	err := m.client.CopyToContainer(ctx, containerID, m.workDir, reader, types.CopyToContainerOptions{CopyUIDGID: true})
+	reader.Close()
+	if err != nil {
+		return fmt.Errorf("failed to copy the workspace into the container: %w", err)
+	}
+	return nil
+}
+
+// writeWorkspaceTar writes paths relative to root as a tar archive. Paths
+// that disappeared meanwhile are left out.
+func writeWorkspaceTar(w io.Writer, root string, paths []string, uid, gid int) error {
+	tw := tar.NewWriter(w)
+	for _, p := range paths {
+		full := filepath.Join(root, filepath.FromSlash(p))
+		info, err := os.Lstat(full)
+		if err != nil {
+			continue
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(full); err != nil {
+				continue
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			continue
+		}
+		header.Name = p
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		header.Uid, header.Gid = uid, gid
+		header.Uname, header.Gname = "", ""
+		header.ModTime = info.ModTime().Truncate(time.Second)
+		header.AccessTime, header.ChangeTime = time.Time{}, time.Time{}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			file, err := os.Open(full)
+			if err != nil {
+				return err
+			}
+			// The file may have changed since it was stat'ed, the header
+			// decides the size
+			_, err = io.CopyN(tw, io.MultiReader(file, zeroReader{}), header.Size)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Close()
+}
+
+// zeroReader pads files that shrank while they were archived
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// pullPaths copies paths of the workspace in the container to the local
+// workspace
+func (m *Manager) pullPaths(ctx context.Context, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	reader, writer := io.Pipe()
+	extracted := make(chan error, 1)
+	go func() {
+		err := extractWorkspaceTar(reader, m.workDir, m.errOut())
+		// Drain the rest so the command does not block
+		io.Copy(io.Discard, reader)
+		extracted <- err
+	}()
+
+	var stderr bytes.Buffer
+	exitCode, err := m.Exec(ctx, ExecOptions{
+		Cmd:        []string{"tar", "--null", "--no-recursion", "--ignore-failed-read", "-cf", "-", "-T", "-"},
+		User:       "root",
+		WorkingDir: m.workDir,
+		Stdin:      strings.NewReader(strings.Join(paths, "\x00") + "\x00"),
+		Stdout:     writer,
+		Stderr:     &stderr,
+	})
+	writer.Close()
+	extractErr := <-extracted
+
+	if err != nil {
+		return fmt.Errorf("failed to copy the workspace from the container: %w", err)
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("failed to copy the workspace from the container: %s", strings.TrimSpace(stderr.String()))
+	}
+	if extractErr != nil {
+		return fmt.Errorf("failed to extract the workspace from the container: %w", extractErr)
+	}
+	return nil
+}
+
+// extractWorkspaceTar writes the files of a tar archive below root. Files
+// are replaced atomically, so editors never see them half written. Entries
+// that would be written through a symlink and symlinks pointing outside of
+// root are skipped with a warning to warnings, either could change files
+// outside of the workspace.
+func extractWorkspaceTar(r io.Reader, root string, warnings io.Writer) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(header.Name)
+		if name == "." || name == ".." || path.IsAbs(name) || strings.HasPrefix(name, "../") {
+			continue
+		}
+		target := filepath.Join(root, filepath.FromSlash(name))
+		perm := os.FileMode(header.Mode).Perm()
+
+		switch header.Typeflag {
+		case tar.TypeDir, tar.TypeReg, tar.TypeSymlink:
+		default:
+			continue
+		}
+		if err := mkdirParents(root, name); err != nil {
+			if errors.Is(err, errSymlinkParent) {
+				fmt.Fprintf(warnings, "Warning: not syncing %s back, %v\n", name, err)
+				continue
+			}
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if info, err := os.Lstat(target); err == nil && !info.IsDir() {
+				os.Remove(target)
+			}
+			if err := os.Mkdir(target, 0755); err != nil && !os.IsExist(err) {
+				return err
+			}
+			os.Chmod(target, perm)
+
+		case tar.TypeReg:
+			dir := filepath.Dir(target)
+			tmp, err := os.CreateTemp(dir, ".claudeway-sync-*")
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(tmp, tr)
+			if closeErr := tmp.Close(); err == nil {
+				err = closeErr
+			}
+			if err == nil {
+				err = os.Chmod(tmp.Name(), perm)
+			}
+			if err == nil {
+				if info, statErr := os.Lstat(target); statErr == nil && info.IsDir() {
+					os.RemoveAll(target)
+				}
+				err = os.Rename(tmp.Name(), target)
+			}
+			if err != nil {
+				os.Remove(tmp.Name())
+				return err
+			}
+			os.Chtimes(target, header.ModTime, header.ModTime)
+
+		case tar.TypeSymlink:
+			if linkEscapes(name, header.Linkname) {
+				fmt.Fprintf(warnings, "Warning: not syncing %s back, it links to %s outside of the workspace\n", name, header.Linkname)
+				continue
+			}
+			os.RemoveAll(target)
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// errSymlinkParent refuses paths below a symlink
+var errSymlinkParent = errors.New("a parent directory is a symlink")
+
+// mkdirParents creates the parent directories of name below root. Unlike
+// os.MkdirAll it does not follow symlinks, a parent that is one is refused.
+func mkdirParents(root, name string) error {
+	dir := root
+	for _, part := range strings.Split(path.Dir(name), "/") {
+		if part == "." {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			if err := os.Mkdir(dir, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %s", errSymlinkParent, dir)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+	}
+	return nil
+}
+
+// linkEscapes reports whether the symlink name pointing to link resolves
+// outside of the workspace
+func linkEscapes(name, link string) bool {
+	if path.IsAbs(link) || filepath.IsAbs(link) || filepath.VolumeName(link) != "" {
+		return true
+	}
+	resolved := path.Clean(path.Join(path.Dir(name), filepath.ToSlash(link)))
+	return resolved == ".." || strings.HasPrefix(resolved, "../")
+}
+
+// deleteRemote deletes paths of the workspace in the container
+func (m *Manager) deleteRemote(ctx context.Context, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	var stderr bytes.Buffer
+	exitCode, err := m.Exec(ctx, ExecOptions{
+		Cmd:        []string{"xargs", "-0", "rm", "-rf", "--"},
+		User:       "root",
+		WorkingDir: m.workDir,
+		Stdin:      strings.NewReader(strings.Join(paths, "\x00")),
+		Stderr:     &stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete files in the container: %w", err)
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("failed to delete files in the container: %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// uploadCopies puts the sources of copy entries where the guest agent
+// expects them, since they cannot be bind-mounted from a remote daemon
+func (m *Manager) uploadCopies(ctx context.Context, containerID string, sources map[string]string) error {
+	if len(sources) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	dirs := map[string]bool{}
+	for local, target := range sources {
+		name := strings.TrimPrefix(filepath.ToSlash(target), "/")
+		for dir := path.Dir(name); dir != "." && !dirs[dir]; dir = path.Dir(dir) {
+			dirs[dir] = true
+			if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0755}); err != nil {
+				return err
+			}
+		}
+
+		err := filepath.Walk(local, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(local, p)
+			if err != nil {
+				return err
+			}
+			entry := path.Join(name, filepath.ToSlash(rel))
+			if info.IsDir() {
+				return tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: entry + "/", Mode: int64(info.Mode().Perm())})
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			if err := tw.WriteHeader(&tar.Header{Name: entry, Mode: int64(info.Mode().Perm()), Size: int64(len(data))}); err != nil {
+				return err
+			}
+			_, err = tw.Write(data)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", local, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	if err := m.client.CopyToContainer(ctx, containerID, "/", &buf, types.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("failed to copy files into the container: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+// Command claudeway-guest is the entrypoint of claudeway containers, see
+// package guest.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/common-creation/claudeway/internal/guest"
+)
+
+func main() {
+	if err := guest.Main(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "claudeway-guest: %v\n", err)
+		os.Exit(guest.ExitCode(err))
+	}
+}
@@ -0,0 +1,71 @@
+package guest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// Main runs the guest agent with the given arguments. Without arguments it
+// initializes the container from the spec and keeps running as its init
+// process. 'step <json> [<user json>]' runs a single init step, which is how
+// steps are baked into an init image.
+func Main(args []string) error {
+	switch {
+	case len(args) == 0:
+		return runInit()
+	case (len(args) == 2 || len(args) == 3) && args[0] == "step":
+		var step Step
+		if err := json.Unmarshal([]byte(args[1]), &step); err != nil {
+			return fmt.Errorf("invalid step: %w", err)
+		}
+		var user *User
+		if len(args) == 3 {
+			if err := json.Unmarshal([]byte(args[2]), &user); err != nil {
+				return fmt.Errorf("invalid user: %w", err)
+			}
+		}
+		return runStepCommand(step, user)
+	}
+	return fmt.Errorf("usage: claudeway-guest [step <json> [<user json>]]")
+}
+
+// exitError makes the agent exit with the code of a failed step
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// ExitCode returns the exit code the agent should exit with for err
+func ExitCode(err error) int {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) && exitErr.code > 0 {
+		return exitErr.code
+	}
+	return 1
+}
+
+// normalizeArch maps uname architecture names to Go's
+func normalizeArch(arch string) string {
+	switch arch {
+	case "x86_64":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	}
+	return arch
+}
+
+// archMatches reports whether arch names the architecture the agent runs on
+func archMatches(arch string) bool {
+	return normalizeArch(arch) == runtime.GOARCH
+}
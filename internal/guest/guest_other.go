@@ -0,0 +1,15 @@
+//go:build !linux
+
+package guest
+
+import "errors"
+
+var errUnsupported = errors.New("the guest agent only runs in Linux containers")
+
+func runInit() error {
+	return errUnsupported
+}
+
+func runStepCommand(step Step, user *User) error {
+	return errUnsupported
+}
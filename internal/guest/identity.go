@@ -0,0 +1,307 @@
+package guest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// account is an entry of /etc/passwd
+type account struct {
+	name string
+	uid  int
+	gid  int
+	home string
+}
+
+// groupEntry is an entry of /etc/group
+type groupEntry struct {
+	name    string
+	gid     int
+	members []string
+}
+
+func (g *groupEntry) hasMember(name string) bool {
+	for _, member := range g.members {
+		if member == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePasswd parses the accounts of an /etc/passwd file, skipping lines it
+// does not understand
+func parsePasswd(data string) []*account {
+	var accounts []*account
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) < 7 {
+			continue
+		}
+		uid, err1 := strconv.Atoi(fields[2])
+		gid, err2 := strconv.Atoi(fields[3])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		accounts = append(accounts, &account{name: fields[0], uid: uid, gid: gid, home: fields[5]})
+	}
+	return accounts
+}
+
+// parseGroup parses the groups of an /etc/group file, skipping lines it does
+// not understand
+func parseGroup(data string) []*groupEntry {
+	var groups []*groupEntry
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) < 4 {
+			continue
+		}
+		gid, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		group := &groupEntry{name: fields[0], gid: gid}
+		if fields[3] != "" {
+			group.members = strings.Split(fields[3], ",")
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// identityPlan is the identity chosen for a user and the commands that
+// change the image's accounts to match it
+type identityPlan struct {
+	Identity
+	commands [][]string
+}
+
+func (p *identityPlan) run(change string, command ...string) {
+	p.Changes = append(p.Changes, change)
+	p.commands = append(p.commands, command)
+}
+
+// planIdentity reconciles the host user with the accounts of the image. The
+// UID is what matters for file ownership, so it always wins:
+//
+//   - An account that already has the UID is renamed to the user's name.
+//   - A regular account that has the name but another UID is renamed to
+//     <name>-<uid> first. System accounts are never renamed, the user is
+//     named <name>-<uid> instead.
+//   - Another account whose home is the user's home gets /home/<its name>
+//     instead, or /home/<its name>-<uid> if that is the user's home.
+//   - An existing group with the GID is used as the primary group. It is
+//     renamed along with its account if it is that account's personal group.
+//   - Missing groups are created with the requested name, or <name>-<gid> if
+//     the name is taken. GID 0 is never added.
+//
+// Running it again on the result plans no commands.
+func planIdentity(u *User, groups []Group, accounts []*account, groupEntries []*groupEntry) *identityPlan {
+	db := &accountDB{accounts: accounts, groups: groupEntries}
+	p := &identityPlan{Identity: Identity{Name: u.Name, UID: u.UID, GID: u.GID, Home: u.Home}}
+
+	byUID := db.accountByUID(u.UID)
+	if byName := db.accountByName(u.Name); byName != nil && byName != byUID {
+		if isSystemUID(byName.uid) {
+			p.Name = db.uniqueAccountName(fmt.Sprintf("%s-%d", u.Name, u.UID))
+			p.Changes = append(p.Changes, fmt.Sprintf("name %s belongs to system account (uid %d), using %s", u.Name, byName.uid, p.Name))
+		} else {
+			newName := db.uniqueAccountName(fmt.Sprintf("%s-%d", byName.name, byName.uid))
+			p.run(fmt.Sprintf("renamed user %s (uid %d) to %s", byName.name, byName.uid, newName),
+				"usermod", "-l", newName, "-d", "/home/"+newName, byName.name)
+			db.renameAccount(byName, newName)
+			byName.home = "/home/" + newName
+		}
+	}
+
+	// The home directory belongs to the user
+	for _, a := range db.accounts {
+		if a == byUID || a.home != u.Home {
+			continue
+		}
+		home := "/home/" + a.name
+		if home == u.Home {
+			home = fmt.Sprintf("/home/%s-%d", a.name, a.uid)
+		}
+		p.run(fmt.Sprintf("moved home of user %s to %s", a.name, home), "usermod", "-d", home, a.name)
+		a.home = home
+	}
+
+	// Primary group
+	primary := db.groupByGID(u.GID)
+	switch {
+	case primary == nil:
+		name := db.uniqueGroupName(p.Name, u.GID)
+		p.run(fmt.Sprintf("created group %s (gid %d)", name, u.GID), "groupadd", "-g", strconv.Itoa(u.GID), name)
+		primary = db.addGroup(name, u.GID)
+	case byUID != nil && byUID.name != p.Name && primary.name == byUID.name && db.groupByName(p.Name) == nil:
+		p.run(fmt.Sprintf("renamed group %s (gid %d) to %s", primary.name, u.GID, p.Name), "groupmod", "-n", p.Name, primary.name)
+		primary.name = p.Name
+	}
+	p.Group = primary.name
+
+	// The account itself
+	uid := strconv.Itoa(u.UID)
+	gid := strconv.Itoa(u.GID)
+	switch {
+	case byUID == nil:
+		p.run(fmt.Sprintf("created user %s (uid %d)", p.Name, u.UID),
+			"useradd", "-u", uid, "-g", gid, "-m", "-d", u.Home, "-s", "/bin/bash", p.Name)
+		db.accounts = append(db.accounts, &account{name: p.Name, uid: u.UID, gid: u.GID, home: u.Home})
+	case byUID.name != p.Name:
+		p.run(fmt.Sprintf("renamed user %s (uid %d) to %s", byUID.name, u.UID, p.Name),
+			"usermod", "-l", p.Name, "-g", gid, "-d", u.Home, "-s", "/bin/bash", byUID.name)
+		db.renameAccount(byUID, p.Name)
+	case byUID.gid != u.GID || byUID.home != u.Home:
+		p.run(fmt.Sprintf("changed primary group and home of user %s", p.Name),
+			"usermod", "-g", gid, "-d", u.Home, p.Name)
+	}
+
+	// Supplementary groups, in a stable order
+	sorted := append([]Group(nil), groups...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].GID < sorted[j].GID })
+	var missing []string
+	seen := map[int]bool{0: true, u.GID: true}
+	for _, want := range sorted {
+		if seen[want.GID] {
+			continue
+		}
+		seen[want.GID] = true
+
+		group := db.groupByGID(want.GID)
+		if group == nil {
+			name := want.Name
+			if name == "" {
+				name = "group"
+			}
+			name = db.uniqueGroupName(name, want.GID)
+			p.run(fmt.Sprintf("created group %s (gid %d)", name, want.GID), "groupadd", "-g", strconv.Itoa(want.GID), name)
+			group = db.addGroup(name, want.GID)
+		}
+		p.Groups = append(p.Groups, Group{Name: group.name, GID: group.gid})
+		if !group.hasMember(p.Name) {
+			missing = append(missing, group.name)
+			group.members = append(group.members, p.Name)
+		}
+	}
+	if len(missing) > 0 {
+		list := strings.Join(missing, ",")
+		p.run(fmt.Sprintf("added user %s to %s", p.Name, list), "usermod", "-a", "-G", list, p.Name)
+	}
+
+	return p
+}
+
+// existingIdentity uses the account the runtime added for the user instead
+// of changing the image's accounts. It returns nil if there is no account
+// with the UID.
+func existingIdentity(u *User, accounts []*account, groupEntries []*groupEntry) *identityPlan {
+	db := &accountDB{accounts: accounts, groups: groupEntries}
+	found := db.accountByUID(u.UID)
+	if found == nil {
+		return nil
+	}
+
+	p := &identityPlan{Identity: Identity{Name: found.name, UID: u.UID, GID: found.gid, Home: u.Home}}
+	if group := db.groupByGID(found.gid); group != nil {
+		p.Group = group.name
+	} else {
+		p.Group = strconv.Itoa(found.gid)
+	}
+	for _, group := range db.groups {
+		if group.gid != found.gid && group.hasMember(found.name) {
+			p.Groups = append(p.Groups, Group{Name: group.name, GID: group.gid})
+		}
+	}
+	return p
+}
+
+// isSystemUID reports whether uid belongs to a system account, which are
+// never renamed
+func isSystemUID(uid int) bool {
+	return uid < 1000 || uid >= 60000
+}
+
+// accountDB tracks the accounts and groups while a plan is made
+type accountDB struct {
+	accounts []*account
+	groups   []*groupEntry
+}
+
+func (db *accountDB) accountByUID(uid int) *account {
+	for _, a := range db.accounts {
+		if a.uid == uid {
+			return a
+		}
+	}
+	return nil
+}
+
+func (db *accountDB) accountByName(name string) *account {
+	for _, a := range db.accounts {
+		if a.name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+func (db *accountDB) groupByGID(gid int) *groupEntry {
+	for _, g := range db.groups {
+		if g.gid == gid {
+			return g
+		}
+	}
+	return nil
+}
+
+func (db *accountDB) groupByName(name string) *groupEntry {
+	for _, g := range db.groups {
+		if g.name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+func (db *accountDB) addGroup(name string, gid int) *groupEntry {
+	group := &groupEntry{name: name, gid: gid}
+	db.groups = append(db.groups, group)
+	return group
+}
+
+// renameAccount renames an account, including its group memberships like
+// usermod -l does
+func (db *accountDB) renameAccount(a *account, name string) {
+	for _, g := range db.groups {
+		for i, member := range g.members {
+			if member == a.name {
+				g.members[i] = name
+			}
+		}
+	}
+	a.name = name
+}
+
+func (db *accountDB) uniqueAccountName(name string) string {
+	candidate := name
+	for i := 2; db.accountByName(candidate) != nil; i++ {
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+	return candidate
+}
+
+// uniqueGroupName returns name if no group has it yet, or <name>-<gid>
+func (db *accountDB) uniqueGroupName(name string, gid int) string {
+	if db.groupByName(name) == nil {
+		return name
+	}
+	candidate := fmt.Sprintf("%s-%d", name, gid)
+	for i := 2; db.groupByName(candidate) != nil; i++ {
+		candidate = fmt.Sprintf("%s-%d-%d", name, gid, i)
+	}
+	return candidate
+}
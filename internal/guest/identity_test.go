@@ -0,0 +1,160 @@
+package guest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanIdentity(t *testing.T) {
+	user := &User{Name: "alice", UID: 1000, GID: 1000, Home: "/home/alice"}
+
+	tests := []struct {
+		name     string
+		passwd   string
+		group    string
+		groups   []Group
+		want     Identity
+		commands [][]string
+	}{
+		{
+			name:   "fresh image",
+			passwd: "root:x:0:0:root:/root:/bin/bash\n",
+			group:  "root:x:0:\n",
+			want:   Identity{Name: "alice", Group: "alice"},
+			commands: [][]string{
+				{"groupadd", "-g", "1000", "alice"},
+				{"useradd", "-u", "1000", "-g", "1000", "-m", "-d", "/home/alice", "-s", "/bin/bash", "alice"},
+			},
+		},
+		{
+			name:   "uid taken by another name",
+			passwd: "ubuntu:x:1000:1000::/home/ubuntu:/bin/bash\n",
+			group:  "ubuntu:x:1000:\n",
+			want:   Identity{Name: "alice", Group: "alice"},
+			commands: [][]string{
+				{"groupmod", "-n", "alice", "ubuntu"},
+				{"usermod", "-l", "alice", "-g", "1000", "-d", "/home/alice", "-s", "/bin/bash", "ubuntu"},
+			},
+		},
+		{
+			name:   "gid taken",
+			passwd: "root:x:0:0:root:/root:/bin/bash\n",
+			group:  "staff:x:1000:\n",
+			want:   Identity{Name: "alice", Group: "staff"},
+			commands: [][]string{
+				{"useradd", "-u", "1000", "-g", "1000", "-m", "-d", "/home/alice", "-s", "/bin/bash", "alice"},
+			},
+		},
+		{
+			name:   "name taken with another uid",
+			passwd: "alice:x:1001:1001::/home/alice:/bin/bash\n",
+			group:  "alice:x:1001:\n",
+			want:   Identity{Name: "alice", Group: "alice-1000"},
+			commands: [][]string{
+				{"usermod", "-l", "alice-1001", "-d", "/home/alice-1001", "alice"},
+				{"groupadd", "-g", "1000", "alice-1000"},
+				{"useradd", "-u", "1000", "-g", "1000", "-m", "-d", "/home/alice", "-s", "/bin/bash", "alice"},
+			},
+		},
+		{
+			name:   "name taken by a system account",
+			passwd: "alice:x:999:999::/var/lib/alice:/usr/sbin/nologin\n",
+			group:  "alice:x:999:\n",
+			want:   Identity{Name: "alice-1000", Group: "alice-1000"},
+			commands: [][]string{
+				{"groupadd", "-g", "1000", "alice-1000"},
+				{"useradd", "-u", "1000", "-g", "1000", "-m", "-d", "/home/alice", "-s", "/bin/bash", "alice-1000"},
+			},
+		},
+		{
+			name:   "home taken",
+			passwd: "dev:x:1001:1001::/home/alice:/bin/bash\n",
+			group:  "dev:x:1001:\n",
+			want:   Identity{Name: "alice", Group: "alice"},
+			commands: [][]string{
+				{"usermod", "-d", "/home/dev", "dev"},
+				{"groupadd", "-g", "1000", "alice"},
+				{"useradd", "-u", "1000", "-g", "1000", "-m", "-d", "/home/alice", "-s", "/bin/bash", "alice"},
+			},
+		},
+		{
+			name:   "supplementary groups",
+			passwd: "alice:x:1000:1000::/home/alice:/bin/bash\n",
+			group:  "alice:x:1000:\nsudo:x:27:alice\ndocker:x:998:\n",
+			groups: []Group{
+				{Name: "docker", GID: 2001},
+				{Name: "root", GID: 0},
+				{Name: "docker", GID: 998},
+				{Name: "alice", GID: 1000},
+				{Name: "render", GID: 2000},
+				{Name: "sudo", GID: 27},
+				{Name: "render", GID: 2000},
+			},
+			want: Identity{Name: "alice", Group: "alice", Groups: []Group{
+				{Name: "sudo", GID: 27},
+				{Name: "docker", GID: 998},
+				{Name: "render", GID: 2000},
+				{Name: "docker-2001", GID: 2001},
+			}},
+			commands: [][]string{
+				{"groupadd", "-g", "2000", "render"},
+				{"groupadd", "-g", "2001", "docker-2001"},
+				{"usermod", "-a", "-G", "docker,render,docker-2001", "alice"},
+			},
+		},
+		{
+			name:   "already reconciled",
+			passwd: "alice:x:1000:1000::/home/alice:/bin/bash\n",
+			group:  "alice:x:1000:\nsudo:x:27:alice\ndocker:x:998:alice\n",
+			groups: []Group{{Name: "docker", GID: 998}, {Name: "sudo", GID: 27}},
+			want: Identity{Name: "alice", Group: "alice", Groups: []Group{
+				{Name: "sudo", GID: 27},
+				{Name: "docker", GID: 998},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := planIdentity(user, tt.groups, parsePasswd(tt.passwd), parseGroup(tt.group))
+
+			if plan.Name != tt.want.Name || plan.Group != tt.want.Group {
+				t.Errorf("identity = %s in group %s, want %s in group %s", plan.Name, plan.Group, tt.want.Name, tt.want.Group)
+			}
+			if plan.UID != user.UID || plan.GID != user.GID || plan.Home != user.Home {
+				t.Errorf("identity = %d:%d at %s, want %d:%d at %s", plan.UID, plan.GID, plan.Home, user.UID, user.GID, user.Home)
+			}
+			if !reflect.DeepEqual(plan.Groups, tt.want.Groups) {
+				t.Errorf("groups = %v, want %v", plan.Groups, tt.want.Groups)
+			}
+			if !reflect.DeepEqual(plan.commands, tt.commands) {
+				t.Errorf("commands = %q, want %q", plan.commands, tt.commands)
+			}
+			if len(plan.Changes) < len(plan.commands) {
+				t.Errorf("%d commands are described by %d changes", len(plan.commands), len(plan.Changes))
+			}
+		})
+	}
+}
+
+func TestExistingIdentity(t *testing.T) {
+	user := &User{Name: "alice", UID: 1000, GID: 1000, Home: "/home/alice"}
+	accounts := parsePasswd("root:x:0:0:root:/root:/bin/bash\nalice2:x:1000:100::/home/alice2:/bin/sh\n")
+	groups := parseGroup("users:x:100:\ndocker:x:998:alice2\nvideo:x:44:\n")
+
+	plan := existingIdentity(user, accounts, groups)
+	if plan == nil {
+		t.Fatal("expected the account with the UID to be used")
+	}
+	want := Identity{Name: "alice2", UID: 1000, GID: 100, Home: "/home/alice", Group: "users", Groups: []Group{{Name: "docker", GID: 998}}}
+	if !reflect.DeepEqual(plan.Identity, want) {
+		t.Errorf("identity = %+v, want %+v", plan.Identity, want)
+	}
+	if len(plan.commands) != 0 {
+		t.Errorf("existing identity planned commands: %q", plan.commands)
+	}
+
+	if existingIdentity(&User{Name: "bob", UID: 1001}, accounts, groups) != nil {
+		t.Error("expected no identity without an account with the UID")
+	}
+}
@@ -0,0 +1,263 @@
+package guest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// task is a single step of the initialization as reported in the status
+type task struct {
+	name            string
+	when            *Condition
+	continueOnError bool
+	run             func(out io.Writer) error
+	// after runs once the task succeeded
+	after func()
+}
+
+// status writes events to the status file
+type status struct {
+	file *os.File
+}
+
+func newStatus() (*status, error) {
+	if err := os.RemoveAll(StatusDir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(StatusDir, "steps"), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(StatusFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &status{file: file}, nil
+}
+
+func (s *status) emit(event Event) {
+	if event.Time == 0 {
+		event.Time = Now()
+	}
+	data, _ := json.Marshal(event)
+	// A single write keeps lines whole for readers
+	s.file.Write(append(data, '\n'))
+}
+
+func runInit() error {
+	st, err := newStatus()
+	if err != nil {
+		return fmt.Errorf("failed to create status: %w", err)
+	}
+	st.emit(Event{Event: "begin"})
+
+	// Stop immediately on docker stop instead of waiting for the kill timeout
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-signals
+		os.Exit(0)
+	}()
+
+	spec, err := readSpec()
+	if err != nil {
+		fmt.Println("Claudeway initialization failed.")
+		st.emit(Event{Event: "failed"})
+		return err
+	}
+
+	// Initialization is skipped on restart unless the configuration changed
+	hash := initHash(spec)
+	skip := false
+	if marker, err := os.ReadFile(InitMarker); err == nil && strings.TrimSpace(string(marker)) == hash {
+		fmt.Println("Initialization already done for this configuration, skipping.")
+		skip = true
+	}
+	os.Remove(InitMarker)
+
+	tasks := planTasks(spec, skip, st)
+	for i, t := range tasks {
+		number := i + 1
+		if t.when != nil && !conditionMatches(t.when) {
+			now := Now()
+			st.emit(Event{Event: "start", Step: number, Total: len(tasks), Name: t.name, Time: now})
+			st.emit(Event{Event: "end", Step: number, Skipped: true, Time: now})
+			continue
+		}
+
+		code := runTask(st, number, len(tasks), t)
+		if code == 0 {
+			if t.after != nil {
+				t.after()
+			}
+			continue
+		}
+		if t.continueOnError {
+			fmt.Printf("  Ignoring failure of: %s\n", t.name)
+			continue
+		}
+
+		fmt.Printf("  ERROR: Command failed: %s\n", t.name)
+		fmt.Println("Claudeway initialization failed.")
+		st.emit(Event{Event: "failed", Step: number})
+		// Exit with an error so the container stops
+		return &exitError{code: 1, err: fmt.Errorf("init step %d failed", number)}
+	}
+
+	if skip {
+		st.emit(Event{Event: "skipped"})
+	}
+	if err := os.WriteFile(InitMarker, []byte(hash+"\n"), 0644); err != nil {
+		fmt.Printf("Warning: failed to write init marker: %v\n", err)
+	}
+	fmt.Println("Claudeway initialization complete.")
+	st.emit(Event{Event: "complete"})
+
+	// Keep running as the container's init process
+	reapChildren()
+	return nil
+}
+
+func readSpec() (*Spec, error) {
+	data, err := os.ReadFile(SpecPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec: %w", err)
+	}
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse spec: %w", err)
+	}
+	return &spec, nil
+}
+
+// initHash identifies the parts of the spec that are skipped on restart
+func initHash(spec *Spec) string {
+	data, _ := json.Marshal(struct {
+		Copy []CopyEntry
+		Init []Step
+	}{spec.Copy, spec.Init})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// planTasks returns the tasks to run in order. User setup and cache volumes
+// are prepared on every start, copies and init steps only if not skipped.
+func planTasks(spec *Spec, skip bool, st *status) []task {
+	var tasks []task
+
+	if spec.User != nil {
+		u := spec.User
+		tasks = append(tasks, task{
+			name: "Set up user " + u.Name,
+			run: func(out io.Writer) error {
+				identity, err := provisionUser(u, out)
+				if err != nil {
+					return err
+				}
+				// The host reports the mapping in 'claudeway status'
+				data, err := json.Marshal(identity)
+				if err != nil {
+					return err
+				}
+				return os.WriteFile(IdentityFile, data, 0644)
+			},
+			after: func() {
+				// Later steps see the user's home
+				os.Setenv("HOME", u.Home)
+				os.Setenv("USER", u.Name)
+				// Sessions may start as soon as the user exists
+				st.emit(Event{Event: "user_ready"})
+			},
+		})
+	}
+
+	if len(spec.Caches) > 0 {
+		tasks = append(tasks, task{
+			name: "Prepare cache volumes",
+			run:  func(out io.Writer) error { return prepareCaches(spec.Caches, spec.User, out) },
+		})
+	}
+
+	if skip {
+		return tasks
+	}
+
+	if len(spec.Copy) > 0 {
+		tasks = append(tasks, task{
+			name: "Copy files",
+			run:  func(out io.Writer) error { return copyFiles(spec.Copy, spec.User, out) },
+		})
+	}
+
+	for _, step := range spec.Init {
+		step := step
+		tasks = append(tasks, task{
+			name:            step.Name,
+			when:            step.When,
+			continueOnError: step.ContinueOnError,
+			run:             func(out io.Writer) error { return runStep(step, spec.User, out) },
+		})
+	}
+	return tasks
+}
+
+// runTask runs a task, recording its start, end, exit code and duration and
+// keeping its output in the steps directory. It returns the exit code.
+func runTask(st *status, number, total int, t task) int {
+	start := time.Now()
+	st.emit(Event{Event: "start", Step: number, Total: total, Name: t.name, Time: start.UnixMilli()})
+
+	out := io.Writer(os.Stdout)
+	if log, err := os.Create(filepath.Join(StatusDir, "steps", fmt.Sprintf("%d.log", number))); err == nil {
+		defer log.Close()
+		out = io.MultiWriter(os.Stdout, log)
+	}
+
+	code := 0
+	if err := t.run(out); err != nil {
+		code = stepExitCode(err)
+		// A command's own exit status needs no explanation
+		var cmdErr *exec.ExitError
+		if !errors.As(err, &cmdErr) {
+			fmt.Fprintf(out, "%v\n", err)
+		}
+	}
+
+	end := time.Now()
+	st.emit(Event{
+		Event:      "end",
+		Step:       number,
+		ExitCode:   code,
+		Ignored:    code != 0 && t.continueOnError,
+		DurationMs: end.Sub(start).Milliseconds(),
+		Time:       end.UnixMilli(),
+	})
+	return code
+}
+
+// reapChildren waits for orphaned processes re-parented to the init process
+// until the container is stopped. It must only run once the agent's own
+// children have exited.
+func reapChildren() {
+	children := make(chan os.Signal, 16)
+	signal.Notify(children, syscall.SIGCHLD)
+	for range children {
+		for {
+			var status syscall.WaitStatus
+			pid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+			if pid <= 0 || err != nil {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package guest is the agent that runs as the entrypoint of claudeway
+// containers. It provisions the container user, copies files, prepares cache
+// volumes and runs the init steps described by a Spec the host writes into
+// the container, and reports its progress as events in a status file.
+//
+// The package only uses the standard library. It is compiled into a static
+// binary for every supported architecture by 'go generate ./internal/guestbin'
+// and embedded into claudeway.
+package guest
+
+import "time"
+
+// Paths inside the container
+const (
+	// BinaryPath is where the image installs the guest agent
+	BinaryPath = "/usr/local/bin/claudeway-guest"
+	// SpecPath is where the host writes the spec before starting the container
+	SpecPath = "/etc/claudeway/spec.json"
+	// StatusDir holds the status file and the output of every step. It is
+	// recreated on every start of the container.
+	StatusDir = "/tmp/.claudeway"
+	// StatusFile receives one JSON Event per line
+	StatusFile = StatusDir + "/status.jsonl"
+	// IdentityFile holds the Identity the agent chose for the user
+	IdentityFile = StatusDir + "/identity.json"
+	// InitMarker holds the hash of the copy and init configuration of the
+	// last successful initialization, so a restart can skip it
+	InitMarker = "/tmp/.claudeway_init_complete"
+)
+
+// Spec describes how to initialize a container
+type Spec struct {
+	// User is the container user mapped to the host user, nil to run
+	// everything as root
+	User   *User       `json:"user,omitempty"`
+	Copy   []CopyEntry `json:"copy,omitempty"`
+	Caches []string    `json:"caches,omitempty"`
+	Init   []Step      `json:"init,omitempty"`
+}
+
+// User is the container user to provision
+type User struct {
+	Name string `json:"name"`
+	UID  int    `json:"uid"`
+	GID  int    `json:"gid"`
+	Home string `json:"home"`
+	// Groups are the host user's supplementary groups
+	Groups []Group `json:"groups,omitempty"`
+	// Existing is set when the runtime already added the account, like
+	// Podman does with --userns=keep-id. It is used as is.
+	Existing bool `json:"existing,omitempty"`
+}
+
+// Group is a group the user is a member of
+type Group struct {
+	Name string `json:"name"`
+	GID  int    `json:"gid"`
+}
+
+// Identity is how the agent mapped the host user to an account of the
+// container, after reconciling it with the accounts of the image
+type Identity struct {
+	Name string `json:"name"`
+	UID  int    `json:"uid"`
+	GID  int    `json:"gid"`
+	Home string `json:"home"`
+	// Group is the name of the primary group
+	Group  string  `json:"group"`
+	Groups []Group `json:"groups,omitempty"`
+	// Changes describes what was changed in the image's accounts
+	Changes []string `json:"changes,omitempty"`
+}
+
+// CopyEntry copies a file or directory from a read-only host mount into the
+// container
+type CopyEntry struct {
+	Source string `json:"source"`
+	Dest   string `json:"dest"`
+}
+
+// Step is a single init command
+type Step struct {
+	Name string `json:"name"`
+	// Run is a bash script run as a login shell
+	Run  string     `json:"run"`
+	When *Condition `json:"when,omitempty"`
+	// AsUser runs the step as the provisioned user instead of root
+	AsUser          bool `json:"as_user,omitempty"`
+	TimeoutSeconds  int  `json:"timeout_seconds,omitempty"`
+	Retries         int  `json:"retries,omitempty"`
+	ContinueOnError bool `json:"continue_on_error,omitempty"`
+}
+
+// Condition limits a step to containers matching all of its fields
+type Condition struct {
+	// FileExists is relative to the working directory or starts with ~/
+	FileExists string `json:"file_exists,omitempty"`
+	// Env must be set to a non-empty value
+	Env string `json:"env,omitempty"`
+	// Arch is compared with the container's architecture, both Go and
+	// uname names are accepted
+	Arch string `json:"arch,omitempty"`
+}
+
+// Event is a single line of the status file
+type Event struct {
+	// Event is one of begin, user_ready, start, end, skipped, complete or
+	// failed
+	Event      string `json:"event"`
+	Step       int    `json:"step,omitempty"`
+	Total      int    `json:"total,omitempty"`
+	Name       string `json:"name,omitempty"`
+	ExitCode   int    `json:"exit_code"`
+	Ignored    bool   `json:"ignored,omitempty"`
+	Skipped    bool   `json:"skipped,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	// Time is in milliseconds since the epoch, by the container's clock
+	Time int64 `json:"time"`
+}
+
+// Now returns the current time as used in events
+func Now() int64 {
+	return time.Now().UnixMilli()
+}
@@ -0,0 +1,143 @@
+package guest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errTimeout is reported with exit code 124 like timeout(1)
+var errTimeout = errors.New("timed out")
+
+// runStep runs an init step, retrying it as configured
+func runStep(step Step, user *User, out io.Writer) error {
+	for attempt := 0; ; attempt++ {
+		err := runStepOnce(step, user, out)
+		if err == nil || attempt >= step.Retries {
+			return err
+		}
+		fmt.Fprintf(out, "Failed with exit code %d, retrying (%d/%d)...\n", stepExitCode(err), attempt+1, step.Retries)
+		time.Sleep(time.Duration(attempt+1) * time.Second)
+	}
+}
+
+func runStepOnce(step Step, user *User, out io.Writer) error {
+	ctx := context.Background()
+	if step.TimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(step.TimeoutSeconds)*time.Second)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "/bin/bash", "-lc", step.Run)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	// Run the step in its own process group so a timeout stops all of it
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	// Background processes started by the step may keep its output open
+	cmd.WaitDelay = 5 * time.Second
+
+	if step.AsUser && user != nil {
+		cmd.SysProcAttr.Credential = &syscall.Credential{
+			Uid:    uint32(user.UID),
+			Gid:    uint32(user.GID),
+			Groups: supplementaryGroups(user),
+		}
+		cmd.Env = userEnv(user)
+	}
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return &exitError{code: 124, err: fmt.Errorf("%w after %ds", errTimeout, step.TimeoutSeconds)}
+	}
+	if errors.Is(err, exec.ErrWaitDelay) {
+		return nil
+	}
+	return err
+}
+
+// runStepCommand runs a single step for 'claudeway-guest step', used when
+// baking steps into an init image. Steps running as the user provision it
+// first, so the image contains the same account the container would create.
+func runStepCommand(step Step, user *User) error {
+	if step.When != nil && !conditionMatches(step.When) {
+		fmt.Println("Condition not met, skipping")
+		return nil
+	}
+
+	if step.AsUser && user != nil {
+		if _, err := provisionUser(user, io.Discard); err != nil {
+			return fmt.Errorf("failed to set up user: %w", err)
+		}
+	}
+
+	err := runStep(step, user, os.Stdout)
+	if err != nil && step.ContinueOnError {
+		fmt.Printf("Ignoring failure with exit code %d\n", stepExitCode(err))
+		return nil
+	}
+	if err != nil {
+		return &exitError{code: stepExitCode(err), err: err}
+	}
+	return nil
+}
+
+// stepExitCode returns the exit code to report for a failed step
+func stepExitCode(err error) int {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	var cmdErr *exec.ExitError
+	if errors.As(err, &cmdErr) {
+		if status, ok := cmdErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return cmdErr.ExitCode()
+	}
+	return 1
+}
+
+// conditionMatches reports whether the container matches all fields of the
+// condition
+func conditionMatches(when *Condition) bool {
+	if when.FileExists != "" {
+		path := when.FileExists
+		if strings.HasPrefix(path, "~/") {
+			path = filepath.Join(os.Getenv("HOME"), path[2:])
+		}
+		if _, err := os.Stat(path); err != nil {
+			return false
+		}
+	}
+	if when.Env != "" && os.Getenv(when.Env) == "" {
+		return false
+	}
+	if when.Arch != "" && !archMatches(when.Arch) {
+		return false
+	}
+	return true
+}
+
+// userEnv returns the agent's environment with the identity of user
+func userEnv(user *User) []string {
+	var env []string
+	for _, entry := range os.Environ() {
+		switch strings.SplitN(entry, "=", 2)[0] {
+		case "HOME", "USER", "LOGNAME":
+			continue
+		}
+		env = append(env, entry)
+	}
+	return append(env, "HOME="+user.Home, "USER="+user.Name, "LOGNAME="+user.Name)
+}
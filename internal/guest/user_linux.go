@@ -0,0 +1,292 @@
+package guest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Lines added to the user's .bashrc
+var bashrcLines = []string{
+	". /opt/asdf/asdf.sh",
+	". /opt/asdf/completions/asdf.bash",
+}
+
+// Sockets whose group the user is added to, so a bound Docker socket is
+// usable without root
+var socketPaths = []string{"/var/run/docker.sock", "/run/docker.sock"}
+
+// provisionUser creates or updates the container account for the host user
+// and returns the identity it chose. The name in u is updated if it had to be
+// changed. It is safe to run on every start.
+func provisionUser(u *User, out io.Writer) (*Identity, error) {
+	passwd, err := os.ReadFile("/etc/passwd")
+	if err != nil {
+		return nil, err
+	}
+	group, err := os.ReadFile("/etc/group")
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, groupEntries := parsePasswd(string(passwd)), parseGroup(string(group))
+	var plan *identityPlan
+	if u.Existing {
+		plan = existingIdentity(u, accounts, groupEntries)
+	}
+	if plan == nil {
+		groups := append(append([]Group(nil), u.Groups...), socketGroups()...)
+		plan = planIdentity(u, groups, accounts, groupEntries)
+	}
+	for _, command := range plan.commands {
+		if err := run(out, command[0], command[1:]...); err != nil {
+			return nil, fmt.Errorf("failed to run %s: %w", strings.Join(command, " "), err)
+		}
+	}
+
+	fmt.Fprintf(out, "Host user %s (%d:%d) is %s in group %s\n", u.Name, u.UID, u.GID, plan.Name, plan.Group)
+	for _, g := range plan.Groups {
+		fmt.Fprintf(out, "  Member of %s (gid %d)\n", g.Name, g.GID)
+	}
+	for _, change := range plan.Changes {
+		fmt.Fprintf(out, "  %s\n", change)
+	}
+	u.Name = plan.Name
+
+	if err := os.MkdirAll(u.Home, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Chown(u.Home, u.UID, u.GID); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(u.Home, 0755); err != nil {
+		return nil, err
+	}
+
+	bashrc := filepath.Join(u.Home, ".bashrc")
+	for _, line := range bashrcLines {
+		if err := appendOnce(bashrc, line); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.Chown(bashrc, u.UID, u.GID); err != nil {
+		return nil, err
+	}
+
+	// Use the tool versions configured for root
+	if data, err := os.ReadFile("/root/.tool-versions"); err == nil {
+		toolVersions := filepath.Join(u.Home, ".tool-versions")
+		if err := os.WriteFile(toolVersions, data, 0644); err != nil {
+			return nil, err
+		}
+		if err := os.Chown(toolVersions, u.UID, u.GID); err != nil {
+			return nil, err
+		}
+	}
+
+	sudoers := fmt.Sprintf("%s ALL=(ALL) NOPASSWD:ALL\n", u.Name)
+	if err := os.WriteFile(filepath.Join("/etc/sudoers.d", u.Name), []byte(sudoers), 0440); err != nil {
+		return nil, err
+	}
+	return &plan.Identity, nil
+}
+
+// socketGroups returns the groups owning the sockets in socketPaths
+func socketGroups() []Group {
+	var groups []Group
+	for _, path := range socketPaths {
+		info, err := os.Stat(path)
+		if err != nil || info.Mode()&os.ModeSocket == 0 {
+			continue
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			groups = append(groups, Group{Name: "docker", GID: int(stat.Gid)})
+		}
+	}
+	return groups
+}
+
+// supplementaryGroups returns the IDs of the groups the user is a member of
+func supplementaryGroups(u *User) []uint32 {
+	found, err := user.Lookup(u.Name)
+	if err != nil {
+		return nil
+	}
+	ids, err := found.GroupIds()
+	if err != nil {
+		return nil
+	}
+
+	var groups []uint32
+	for _, id := range ids {
+		if gid, err := strconv.ParseUint(id, 10, 32); err == nil {
+			groups = append(groups, uint32(gid))
+		}
+	}
+	return groups
+}
+
+// prepareCaches hands the shared cache volumes to the user. Docker creates
+// the mount point and any missing parents as root, so those are fixed up to
+// the home directory too.
+func prepareCaches(caches []string, u *User, out io.Writer) error {
+	asdf := false
+	for _, cache := range caches {
+		if cache == "/opt/asdf/installs" {
+			asdf = true
+		}
+		if u == nil {
+			continue
+		}
+
+		if err := os.Chown(cache, u.UID, u.GID); err != nil {
+			return err
+		}
+		for parent := filepath.Dir(cache); strings.HasPrefix(parent, u.Home+"/"); parent = filepath.Dir(parent) {
+			if err := os.Chown(parent, u.UID, u.GID); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Regenerate asdf shims for tool versions restored from a cache volume
+	if asdf {
+		if err := run(out, "/bin/bash", "-lc", "asdf reshim"); err != nil {
+			fmt.Fprintf(out, "Warning: asdf reshim failed: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// copyFiles copies files and directories from the host mounts and hands
+// them to the user
+func copyFiles(entries []CopyEntry, u *User, out io.Writer) error {
+	for _, entry := range entries {
+		if _, err := os.Lstat(entry.Source); err != nil {
+			fmt.Fprintf(out, "  Warning: Source not found: %s\n", entry.Source)
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(entry.Dest), 0755); err != nil {
+			return err
+		}
+		if err := copyTree(entry.Source, entry.Dest); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", entry.Source, err)
+		}
+		if u != nil {
+			if err := chownTree(entry.Dest, u.UID, u.GID); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(out, "  Copied %s -> %s\n", strings.TrimPrefix(entry.Source, "/host"), entry.Dest)
+	}
+	return nil
+}
+
+// copyTree copies a file, symlink or directory, replacing files that
+// already exist at dest
+func copyTree(src, dest string) error {
+	// Follow a symlink given as the source itself
+	src, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return err
+	}
+
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			os.Remove(target)
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		}
+		// Sockets and devices are not copied
+		return nil
+	})
+}
+
+func copyFile(src, dest string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// Keep the mode of files that already existed
+	return os.Chmod(dest, mode)
+}
+
+func chownTree(root string, uid, gid int) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
+
+// appendOnce appends a line to a file unless it is already present, so
+// restarts stay idempotent
+func appendOnce(path, line string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, existing := range strings.Split(string(data), "\n") {
+		if existing == line {
+			return nil
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		line = "\n" + line
+	}
+	_, err = fmt.Fprintln(f, line)
+	return err
+}
+
+// run runs a command with its output going to out
+func run(out io.Writer, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return cmd.Run()
+}
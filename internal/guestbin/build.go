@@ -0,0 +1,33 @@
+//go:build ignore
+
+// build.go cross-compiles the guest agent into this directory, see
+// 'go generate'
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// architectures the guest agent is built for
+var architectures = []string{"amd64", "arm64"}
+
+// toolchain is the Go release the binaries are built with. Builds are only
+// reproducible with the same release, CI rebuilds them to check the
+// committed ones.
+const toolchain = "go1.27.1"
+
+func main() {
+	for _, arch := range architectures {
+		output := "claudeway-guest-linux-" + arch
+		cmd := exec.Command("go", "build", "-trimpath", "-buildvcs=false", "-ldflags=-s -w", "-o", output, "../guest/agent")
+		cmd.Env = append(os.Environ(), "CGO_ENABLED=0", "GOOS=linux", "GOARCH="+arch, "GOTOOLCHAIN="+toolchain)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to build %s: %v\n", output, err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// Package guestbin embeds the guest agent, cross-compiled for the
+// architectures claudeway supports. The binaries are copied into the image
+// when it is built.
+//
+// Run 'go generate ./internal/guestbin' after changing internal/guest.
+package guestbin
+
+import (
+	"embed"
+	"fmt"
+)
+
+//go:generate go run build.go
+
+//go:embed claudeway-guest-linux-*
+var binaries embed.FS
+
+// Binary returns the guest agent for a Go architecture name
+func Binary(arch string) ([]byte, error) {
+	data, err := binaries.ReadFile("claudeway-guest-linux-" + arch)
+	if err != nil {
+		return nil, fmt.Errorf("the guest agent is not available for %s", arch)
+	}
+	return data, nil
+}
@@ -0,0 +1,272 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/common-creation/claudeway/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Batch is a list of tasks read from a tasks file
+type Batch struct {
+	// Concurrency limits how many tasks run at the same time
+	Concurrency int `yaml:"concurrency"`
+	// Timeout is the default timeout of each task
+	Timeout time.Duration `yaml:"timeout"`
+	// Agent is the default agent preset of each task
+	Agent string      `yaml:"agent"`
+	Tasks []BatchTask `yaml:"tasks"`
+}
+
+// BatchTask is a single entry of a tasks file. Exactly one of Prompt,
+// PromptFile and Command must be set.
+type BatchTask struct {
+	Name       string         `yaml:"name"`
+	Prompt     string         `yaml:"prompt"`
+	PromptFile string         `yaml:"prompt_file"`
+	Command    config.Command `yaml:"command"`
+	Agent      string         `yaml:"agent"`
+	Timeout    time.Duration  `yaml:"timeout"`
+}
+
+// BatchResult is written to summary.json in the batch output directory
+type BatchResult struct {
+	StartedAt       time.Time `json:"started_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Succeeded       int       `json:"succeeded"`
+	Failed          int       `json:"failed"`
+	Tasks           []*Report `json:"tasks"`
+}
+
+var taskNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// LoadBatch reads and validates a tasks file. Relative prompt files are
+// resolved against the directory of the tasks file.
+func LoadBatch(path string) (*Batch, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var batch Batch
+	if err := yaml.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("failed to parse tasks file %s: %w", path, err)
+	}
+	if len(batch.Tasks) == 0 {
+		return nil, fmt.Errorf("no tasks in %s", path)
+	}
+
+	names := make(map[string]bool)
+	for i := range batch.Tasks {
+		t := &batch.Tasks[i]
+		if t.Name == "" {
+			t.Name = fmt.Sprintf("task-%d", i+1)
+		}
+		if !taskNamePattern.MatchString(t.Name) {
+			return nil, fmt.Errorf("task %q: names may only contain letters, digits, '.', '_' and '-'", t.Name)
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("task %q is defined more than once", t.Name)
+		}
+		names[t.Name] = true
+
+		set := 0
+		for _, ok := range []bool{t.Prompt != "", t.PromptFile != "", len(t.Command) > 0} {
+			if ok {
+				set++
+			}
+		}
+		if set != 1 {
+			return nil, fmt.Errorf("task %q: set exactly one of prompt, prompt_file and command", t.Name)
+		}
+
+		if t.PromptFile != "" {
+			promptFile := t.PromptFile
+			if !filepath.IsAbs(promptFile) {
+				promptFile = filepath.Join(filepath.Dir(path), promptFile)
+			}
+			prompt, err := os.ReadFile(promptFile)
+			if err != nil {
+				return nil, fmt.Errorf("task %q: failed to read prompt: %w", t.Name, err)
+			}
+			t.Prompt = string(prompt)
+		}
+	}
+
+	return &batch, nil
+}
+
+// BatchOptions controls how RunBatch runs the tasks
+type BatchOptions struct {
+	// Concurrency overrides the batch's concurrency if positive
+	Concurrency int
+	// OutputDir receives a directory per task and summary.json
+	OutputDir string
+	// Progress is called when a task enters a new phase, and with the
+	// report's status when it is done. It may be called concurrently.
+	Progress func(index int, phase string)
+	// Warn is called with each warning of a task, which is also written to
+	// the task's log. It may be called concurrently.
+	Warn func(index int, message string)
+}
+
+// RunBatch runs every task of the batch in its own ephemeral sandbox and git
+// worktree of dir
+func RunBatch(ctx context.Context, dir string, batch *Batch, options BatchOptions) *BatchResult {
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = batch.Concurrency
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	progress := options.Progress
+	if progress == nil {
+		progress = func(int, string) {}
+	}
+	warn := options.Warn
+	if warn == nil {
+		warn = func(int, string) {}
+	}
+
+	result := &BatchResult{
+		StartedAt: time.Now(),
+		Tasks:     make([]*Report, len(batch.Tasks)),
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range batch.Tasks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+			}
+
+			report := runBatchTask(ctx, dir, batch, i, options.OutputDir, func(phase string) { progress(i, phase) }, func(message string) { warn(i, message) })
+			result.Tasks[i] = report
+			progress(i, report.Status)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, report := range result.Tasks {
+		if report.Status == StatusSuccess {
+			result.Succeeded++
+		} else {
+			result.Failed++
+		}
+	}
+	result.DurationSeconds = time.Since(result.StartedAt).Seconds()
+
+	if data, err := json.MarshalIndent(result, "", "  "); err == nil {
+		os.WriteFile(filepath.Join(options.OutputDir, "summary.json"), data, 0644)
+	}
+
+	return result
+}
+
+func runBatchTask(ctx context.Context, dir string, batch *Batch, index int, outputDir string, progress func(string), warn func(string)) *Report {
+	t := batch.Tasks[index]
+	taskDir := filepath.Join(outputDir, t.Name)
+
+	fail := func(err error) *Report {
+		report := &Report{Name: t.Name, Status: StatusError, StartedAt: time.Now(), Workspace: dir, Error: err.Error()}
+		writeReport(taskDir, report)
+		return report
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fail(err)
+	}
+	if err := os.MkdirAll(taskDir, 0755); err != nil {
+		return fail(fmt.Errorf("failed to create output directory: %w", err))
+	}
+
+	agent := t.Agent
+	if agent == "" {
+		agent = batch.Agent
+	}
+	cfg, err := config.LoadWithAgent(agent)
+	if err != nil {
+		return fail(fmt.Errorf("failed to load configuration: %w", err))
+	}
+
+	timeout := t.Timeout
+	if timeout == 0 {
+		timeout = batch.Timeout
+	}
+
+	log, err := os.Create(filepath.Join(taskDir, "sandbox.log"))
+	if err != nil {
+		return fail(fmt.Errorf("failed to create log: %w", err))
+	}
+	defer log.Close()
+
+	return Run(ctx, cfg, dir, Spec{
+		Name:      t.Name,
+		Prompt:    t.Prompt,
+		Command:   t.Command,
+		Timeout:   timeout,
+		Worktree:  true,
+		OutputDir: taskDir,
+		Log:       &warningWriter{w: log, warn: warn},
+		Progress:  progress,
+	})
+}
+
+// warningWriter passes everything to w and the lines starting with
+// "Warning:" to warn
+type warningWriter struct {
+	w    io.Writer
+	warn func(string)
+	line []byte
+}
+
+func (w *warningWriter) Write(p []byte) (int, error) {
+	w.line = append(w.line, p...)
+	for {
+		i := bytes.IndexByte(w.line, '\n')
+		if i < 0 {
+			break
+		}
+		if line := string(w.line[:i]); strings.HasPrefix(line, "Warning:") {
+			w.warn(line)
+		}
+		w.line = w.line[i+1:]
+	}
+	return w.w.Write(p)
+}
+
+// Summary returns a one-line description of the report for progress output
+func (r *Report) Summary() string {
+	var details []string
+	if r.Status == StatusFailed {
+		details = append(details, fmt.Sprintf("exit code %d", r.ExitCode))
+	}
+	if r.Error != "" {
+		details = append(details, r.Error)
+	}
+	if r.Patch != "" {
+		details = append(details, "changes in "+filepath.Base(r.Patch))
+	}
+	if len(details) == 0 {
+		return r.Status
+	}
+	return fmt.Sprintf("%s (%s)", r.Status, strings.Join(details, ", "))
+}
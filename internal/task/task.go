@@ -0,0 +1,314 @@
+// Package task runs a coding agent or command headlessly in a sandbox and
+// collects what it produced.
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/common-creation/claudeway/internal/config"
+	"github.com/common-creation/claudeway/internal/docker"
+	"github.com/common-creation/claudeway/internal/workspace"
+)
+
+// Task statuses reported in Report.Status
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusTimeout = "timeout"
+	StatusError   = "error"
+)
+
+// Spec describes a single headless run
+type Spec struct {
+	Name string
+	// Prompt is passed on stdin to the configured agent's headless command
+	Prompt string
+	// Command is run instead of the agent if set
+	Command []string
+	// Timeout limits the agent or command run, zero means no limit
+	Timeout time.Duration
+	// Ephemeral runs in a throwaway container instead of the project's one
+	Ephemeral bool
+	// Worktree runs in a fresh git worktree of the project at HEAD. It
+	// implies Ephemeral.
+	Worktree bool
+	// OutputDir receives the transcript, the patch and the report
+	OutputDir string
+	// Log receives progress messages and the container's init output
+	Log io.Writer
+	// Progress is called when the run enters a new phase
+	Progress func(phase string)
+}
+
+// Phases passed to Spec.Progress
+const (
+	PhaseStarting   = "starting"
+	PhaseRunning    = "running"
+	PhaseCollecting = "collecting"
+)
+
+// Report is the result of a run
+type Report struct {
+	Name            string    `json:"name,omitempty"`
+	Status          string    `json:"status"`
+	ExitCode        int       `json:"exit_code"`
+	StartedAt       time.Time `json:"started_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Workspace       string    `json:"workspace"`
+	Transcript      string    `json:"transcript"`
+	Patch           string    `json:"patch,omitempty"`
+	Diff            string    `json:"diff"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// DefaultOutputDir returns a new directory for a run's output under the XDG
+// state directory
+func DefaultOutputDir(name string) string {
+	dirName := time.Now().Format("20060102-150405")
+	if name != "" {
+		dirName += "-" + name
+	}
+	return filepath.Join(config.GetStateDir(), "claudeway", "tasks", dirName)
+}
+
+// Run executes spec in dir and always returns a report. Failures of the run
+// itself are recorded in the report with StatusError.
+func Run(ctx context.Context, cfg *config.Config, dir string, spec Spec) *Report {
+	log := spec.Log
+	if log == nil {
+		log = io.Discard
+	}
+
+	report := &Report{
+		Name:      spec.Name,
+		StartedAt: time.Now(),
+		Workspace: dir,
+	}
+	defer func() {
+		report.DurationSeconds = time.Since(report.StartedAt).Seconds()
+		if err := writeReport(spec.OutputDir, report); err != nil {
+			fmt.Fprintf(log, "Warning: failed to write report: %v\n", err)
+		}
+	}()
+
+	if err := run(ctx, cfg, dir, spec, report, log); err != nil {
+		report.Status = StatusError
+		report.Error = err.Error()
+	}
+	return report
+}
+
+func run(ctx context.Context, cfg *config.Config, dir string, spec Spec, report *Report, log io.Writer) error {
+	command := spec.Command
+	if len(command) == 0 {
+		if cfg.Agent == nil || cfg.Agent.Name == "" {
+			return fmt.Errorf("no agent configured, set 'agent' in claudeway.yaml or pass an agent name")
+		}
+		preset, err := cfg.Agent.Preset()
+		if err != nil {
+			return err
+		}
+		command = append(append([]string{}, preset.Headless...), cfg.Agent.Args...)
+	}
+
+	if err := os.MkdirAll(spec.OutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	progress := spec.Progress
+	if progress == nil {
+		progress = func(string) {}
+	}
+	progress(PhaseStarting)
+
+	if spec.Worktree {
+		root, err := workspace.RepoRoot(dir)
+		if err != nil {
+			return fmt.Errorf("a worktree needs a git repository: %w", err)
+		}
+		worktree, err := os.MkdirTemp("", "claudeway-worktree-")
+		if err != nil {
+			return fmt.Errorf("failed to create worktree directory: %w", err)
+		}
+		// git refuses to create a worktree in an existing directory
+		os.Remove(worktree)
+
+		fmt.Fprintf(log, "Creating worktree %s...\n", worktree)
+		if err := workspace.AddWorktree(root, worktree); err != nil {
+			return fmt.Errorf("failed to create worktree: %w", err)
+		}
+		defer func() {
+			if err := workspace.RemoveWorktree(root, worktree); err != nil {
+				fmt.Fprintf(log, "Warning: failed to remove worktree %s: %v\n", worktree, err)
+			}
+		}()
+
+		// Keep the same position relative to the repository root
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return err
+		}
+		dir = filepath.Join(worktree, rel)
+		report.Workspace = dir
+	}
+
+	manager, err := docker.NewManagerForDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to create docker manager: %w", err)
+	}
+	manager.SetOutput(log)
+
+	if spec.Ephemeral || spec.Worktree {
+		manager, err = manager.Ephemeral()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := manager.StopAndRemoveContainer(context.Background()); err != nil {
+				fmt.Fprintf(log, "Warning: failed to remove container %s: %v\n", manager.GetContainerName(), err)
+			}
+		}()
+	}
+
+	if err := ensureRunning(ctx, manager, cfg, log); err != nil {
+		return err
+	}
+
+	// Snapshot after initialization so only the run's own changes end up in the diff
+	if err := syncBack(ctx, manager); err != nil {
+		return err
+	}
+	before, err := workspace.Snapshot(dir)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot workspace: %w", err)
+	}
+
+	report.Transcript = filepath.Join(spec.OutputDir, "transcript.log")
+	transcript, err := os.Create(report.Transcript)
+	if err != nil {
+		return fmt.Errorf("failed to create transcript: %w", err)
+	}
+	defer transcript.Close()
+
+	runCtx := ctx
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, spec.Timeout)
+		defer cancel()
+	}
+
+	progress(PhaseRunning)
+	fmt.Fprintf(log, "Running %s...\n", strings.Join(command, " "))
+	exitCode, err := manager.ExecAsUser(runCtx, docker.ExecOptions{
+		Cmd:    command,
+		Stdin:  strings.NewReader(spec.Prompt),
+		Stdout: transcript,
+		Stderr: transcript,
+	})
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		report.Status = StatusTimeout
+		report.ExitCode = -1
+		fmt.Fprintf(transcript, "\n[claudeway] timed out after %s\n", spec.Timeout)
+	case err != nil:
+		return err
+	case exitCode != 0:
+		report.Status = StatusFailed
+		report.ExitCode = exitCode
+	default:
+		report.Status = StatusSuccess
+	}
+
+	progress(PhaseCollecting)
+	if err := syncBack(ctx, manager); err != nil {
+		return err
+	}
+	after, err := workspace.Snapshot(dir)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot workspace: %w", err)
+	}
+	report.Diff, err = workspace.Diff(dir, before, after)
+	if err != nil {
+		return fmt.Errorf("failed to diff workspace: %w", err)
+	}
+	if report.Diff != "" {
+		report.Patch = filepath.Join(spec.OutputDir, "changes.patch")
+		if err := os.WriteFile(report.Patch, []byte(report.Diff), 0644); err != nil {
+			return fmt.Errorf("failed to write patch: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// syncBack brings the changes in a synced workspace back to the local
+// workspace, the snapshots only see local files
+func syncBack(ctx context.Context, manager *docker.Manager) error {
+	synced, err := manager.SyncsWorkspace(ctx)
+	if err != nil || !synced {
+		return err
+	}
+	if _, err := manager.SyncWorkspace(ctx); err != nil {
+		return fmt.Errorf("failed to sync the workspace: %w", err)
+	}
+	return nil
+}
+
+// ensureRunning starts the manager's container if needed and waits for its
+// initialization. Unlike 'claudeway up' it never prompts.
+func ensureRunning(ctx context.Context, manager *docker.Manager, cfg *config.Config, log io.Writer) error {
+	running, err := manager.IsContainerRunning(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if running {
+		if drift, err := manager.CheckDrift(ctx, cfg); err == nil && drift != nil {
+			fmt.Fprintf(log, "Warning: container %s is out of date with the current configuration\n", manager.GetContainerName())
+		}
+		return nil
+	}
+
+	exists, err := manager.ContainerExists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if container exists: %w", err)
+	}
+
+	if exists {
+		fmt.Fprintf(log, "Starting stopped container %s...\n", manager.GetContainerName())
+		if err := manager.StartContainer(ctx); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(log, "Starting container %s...\n", manager.GetContainerName())
+		if err := manager.CreateAndStartContainer(ctx, cfg); err != nil {
+			return fmt.Errorf("failed to start container: %w", err)
+		}
+	}
+
+	if err := manager.WaitForInitialization(ctx); err != nil {
+		return fmt.Errorf("initialization failed: %w", err)
+	}
+	return nil
+}
+
+func writeReport(dir string, report *Report) error {
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "report.json"), data, 0644)
+}
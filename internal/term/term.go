@@ -0,0 +1,47 @@
+// Package term provides the terminal handling needed for interactive
+// sessions: raw mode, size queries, resize notifications and terminal
+// detection. Each supported platform has its own implementation selected by
+// build tags.
+package term
+
+// Size is the size of a terminal in character cells
+type Size struct {
+	Height uint16
+	Width  uint16
+}
+
+// State holds the terminal state to restore after raw mode
+type State struct {
+	state
+}
+
+// IsTerminal reports whether fd refers to a terminal
+func IsTerminal(fd uintptr) bool {
+	return isTerminal(fd)
+}
+
+// MakeRaw puts the terminal connected to fd into raw mode and returns the
+// previous state for Restore
+func MakeRaw(fd uintptr) (*State, error) {
+	return makeRaw(fd)
+}
+
+// Restore restores the terminal connected to fd to a state returned by MakeRaw
+func Restore(fd uintptr, state *State) error {
+	if state == nil {
+		return nil
+	}
+	return restore(fd, state)
+}
+
+// GetSize returns the size of the terminal connected to fd
+func GetSize(fd uintptr) (*Size, error) {
+	return getSize(fd)
+}
+
+// NotifyResize sends to ch whenever the terminal is resized, until the
+// returned stop function is called. Sends do not block, so ch should be
+// buffered. Once stop returns, nothing is sent to ch anymore.
+func NotifyResize(ch chan<- struct{}) (stop func()) {
+	return notifyResize(ch)
+}
@@ -0,0 +1,91 @@
+package term
+
+import (
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+type state struct {
+	mode uint32
+}
+
+// resizePollInterval is how often the console size is checked, Windows has
+// no resize signal
+const resizePollInterval = 250 * time.Millisecond
+
+func isTerminal(fd uintptr) bool {
+	var mode uint32
+	return windows.GetConsoleMode(windows.Handle(fd), &mode) == nil
+}
+
+func makeRaw(fd uintptr) (*State, error) {
+	var mode uint32
+	if err := windows.GetConsoleMode(windows.Handle(fd), &mode); err != nil {
+		return nil, err
+	}
+
+	// Pass keys through unprocessed as VT sequences, like a raw Unix terminal
+	raw := mode &^ (windows.ENABLE_ECHO_INPUT | windows.ENABLE_PROCESSED_INPUT | windows.ENABLE_LINE_INPUT)
+	raw |= windows.ENABLE_VIRTUAL_TERMINAL_INPUT
+	if err := windows.SetConsoleMode(windows.Handle(fd), raw); err != nil {
+		return nil, err
+	}
+
+	// The session output contains VT sequences as well
+	var outMode uint32
+	stdout := windows.Handle(windows.Stdout)
+	if windows.GetConsoleMode(stdout, &outMode) == nil {
+		windows.SetConsoleMode(stdout, outMode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+	}
+
+	return &State{state{mode: mode}}, nil
+}
+
+func restore(fd uintptr, state *State) error {
+	return windows.SetConsoleMode(windows.Handle(fd), state.mode)
+}
+
+func getSize(fd uintptr) (*Size, error) {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(fd), &info); err != nil {
+		return nil, err
+	}
+	return &Size{
+		Height: uint16(info.Window.Bottom - info.Window.Top + 1),
+		Width:  uint16(info.Window.Right - info.Window.Left + 1),
+	}, nil
+}
+
+func notifyResize(ch chan<- struct{}) func() {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(resizePollInterval)
+		defer ticker.Stop()
+
+		last, _ := getSize(uintptr(windows.Stdout))
+		for {
+			select {
+			case <-ticker.C:
+				size, err := getSize(uintptr(windows.Stdout))
+				if err != nil || (last != nil && *size == *last) {
+					continue
+				}
+				last = size
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-exited
+	}
+}
@@ -0,0 +1,85 @@
+// Package workspace inspects and prepares project directories on the host.
+package workspace
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// worktreeMu serializes worktree changes, git does not lock the list of
+// worktrees against concurrent updates
+var worktreeMu sync.Mutex
+
+// Snapshot records the current content of the working tree, including
+// untracked files that are not ignored, and returns the hash of a git tree
+// object for it. The repository's index and refs are not touched.
+func Snapshot(dir string) (string, error) {
+	indexDir, err := os.MkdirTemp("", "claudeway-index-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(indexDir)
+
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(indexDir, "index")}
+	if _, err := git(dir, env, "add", "--all", "."); err != nil {
+		return "", err
+	}
+	tree, err := git(dir, env, "write-tree")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(tree), nil
+}
+
+// Diff returns a unified diff between two snapshots
+func Diff(dir, from, to string) (string, error) {
+	return git(dir, nil, "diff", "--binary", from, to)
+}
+
+// AddWorktree creates a detached worktree of the repository in dir at path,
+// checked out at the current HEAD
+func AddWorktree(dir, path string) error {
+	worktreeMu.Lock()
+	defer worktreeMu.Unlock()
+
+	_, err := git(dir, nil, "worktree", "add", "--detach", path, "HEAD")
+	return err
+}
+
+// RemoveWorktree removes a worktree created by AddWorktree, including any
+// changes made in it
+func RemoveWorktree(dir, path string) error {
+	worktreeMu.Lock()
+	defer worktreeMu.Unlock()
+
+	_, err := git(dir, nil, "worktree", "remove", "--force", path)
+	return err
+}
+
+// RepoRoot returns the top-level directory of the git repository containing dir
+func RepoRoot(dir string) (string, error) {
+	root, err := git(dir, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(root), nil
+}
+
+func git(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
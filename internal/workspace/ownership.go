@@ -0,0 +1,45 @@
+package workspace
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// ForeignFile is a path in the workspace that is not owned by the host user
+type ForeignFile struct {
+	Path string
+	UID  int
+	Dir  bool
+}
+
+// FindForeignFiles returns the paths under root that are not owned by uid.
+// A directory that is not owned by uid is returned without its contents,
+// fixing it fixes them too. The .git directory is skipped. Nothing is found
+// on systems without file owners.
+func FindForeignFiles(root string, uid int) ([]ForeignFile, error) {
+	var files []ForeignFile
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories are reported through their owner
+			return nil
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		owner, ok := fileOwner(info)
+		if !ok || owner == uid {
+			return nil
+		}
+
+		files = append(files, ForeignFile{Path: path, UID: owner, Dir: d.IsDir()})
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return files, err
+}
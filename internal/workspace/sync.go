@@ -0,0 +1,265 @@
+package workspace
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Entry types
+const (
+	EntryFile    = "f"
+	EntryDir     = "d"
+	EntrySymlink = "l"
+)
+
+// Entry is the state of a path in a synced workspace
+type Entry struct {
+	Type string `json:"t"`
+	Size int64  `json:"s,omitempty"`
+	// MTime is in whole seconds, the precision both sides keep
+	MTime int64       `json:"m,omitempty"`
+	Mode  fs.FileMode `json:"p,omitempty"`
+	Link  string      `json:"l,omitempty"`
+}
+
+// Same reports whether two entries have the same content as far as sync can
+// tell. Directories are the same if both are directories.
+func (e Entry) Same(other Entry) bool {
+	if e.Type != other.Type {
+		return false
+	}
+	switch e.Type {
+	case EntryFile:
+		return e.Size == other.Size && e.MTime == other.MTime
+	case EntrySymlink:
+		return e.Link == other.Link
+	}
+	return true
+}
+
+// Tree maps slash separated paths relative to the workspace to entries
+type Tree map[string]Entry
+
+// Excluded reports whether any element of a relative path matches one of
+// the patterns
+func Excluded(rel string, exclude []string) bool {
+	for _, name := range strings.Split(rel, "/") {
+		for _, pattern := range exclude {
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ScanLocal lists the workspace at root, leaving out excluded
+// paths. Other file types than regular files, directories and symlinks are
+// ignored.
+func ScanLocal(root string, exclude []string) (Tree, error) {
+	tree := Tree{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if Excluded(rel, exclude) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			// Removed while scanning
+			return nil
+		}
+		entry := Entry{Mode: info.Mode().Perm()}
+		switch {
+		case info.Mode().IsRegular():
+			entry.Type = EntryFile
+			entry.Size = info.Size()
+			entry.MTime = info.ModTime().Unix()
+		case info.IsDir():
+			entry.Type = EntryDir
+		case info.Mode()&fs.ModeSymlink != 0:
+			entry.Type = EntrySymlink
+			if entry.Link, err = os.Readlink(p); err != nil {
+				return nil
+			}
+		default:
+			return nil
+		}
+		tree[rel] = entry
+		return nil
+	})
+	return tree, err
+}
+
+// SyncState is what both sides looked like after the last sync
+type SyncState struct {
+	Local  Tree `json:"local"`
+	Remote Tree `json:"remote"`
+	// Exclude are the patterns of the workspace, kept for later sessions
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// LoadSyncState reads a sync state, an empty one if it does not exist yet
+func LoadSyncState(file string) (*SyncState, error) {
+	state := &SyncState{Local: Tree{}, Remote: Tree{}}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	if state.Local == nil {
+		state.Local = Tree{}
+	}
+	if state.Remote == nil {
+		state.Remote = Tree{}
+	}
+	return state, nil
+}
+
+// Save writes the sync state, replacing the file atomically
+func (s *SyncState) Save(file string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// SyncPlan lists the paths to transfer, sorted so directories come before
+// their contents
+type SyncPlan struct {
+	Push         []string
+	Pull         []string
+	DeleteRemote []string
+	DeleteLocal  []string
+	// Conflicts changed on both sides, the newer one was kept
+	Conflicts []string
+}
+
+// Empty reports whether there is nothing to do
+func (p *SyncPlan) Empty() bool {
+	return len(p.Push) == 0 && len(p.Pull) == 0 && len(p.DeleteRemote) == 0 && len(p.DeleteLocal) == 0
+}
+
+// PlanSync compares both sides with the last sync. A change on one side is
+// applied to the other, deletions included. If both sides changed a path,
+// the newer version wins. Without a last state nothing is deleted.
+func PlanSync(last *SyncState, local, remote Tree) *SyncPlan {
+	paths := map[string]bool{}
+	for _, tree := range []Tree{local, remote, last.Local, last.Remote} {
+		for p := range tree {
+			paths[p] = true
+		}
+	}
+
+	plan := &SyncPlan{}
+	for p := range paths {
+		l, inLocal := local[p]
+		r, inRemote := remote[p]
+		if inLocal && inRemote && l.Same(r) {
+			continue
+		}
+
+		lastL, wasLocal := last.Local[p]
+		lastR, wasRemote := last.Remote[p]
+		localChanged := inLocal != wasLocal || (inLocal && !l.Same(lastL))
+		remoteChanged := inRemote != wasRemote || (inRemote && !r.Same(lastR))
+
+		push := func() {
+			if inRemote && r.Type != l.Type {
+				plan.DeleteRemote = append(plan.DeleteRemote, p)
+			}
+			plan.Push = append(plan.Push, p)
+		}
+		pull := func() {
+			if inLocal && l.Type != r.Type {
+				plan.DeleteLocal = append(plan.DeleteLocal, p)
+			}
+			plan.Pull = append(plan.Pull, p)
+		}
+
+		switch {
+		case localChanged && !remoteChanged:
+			if inLocal {
+				push()
+			} else if inRemote {
+				plan.DeleteRemote = append(plan.DeleteRemote, p)
+			}
+		case remoteChanged && !localChanged:
+			if inRemote {
+				pull()
+			} else if inLocal {
+				plan.DeleteLocal = append(plan.DeleteLocal, p)
+			}
+		default:
+			// Both changed, or the last state does not match either side.
+			// Keeping a path beats deleting it.
+			switch {
+			case inLocal && inRemote:
+				if wasLocal || wasRemote {
+					plan.Conflicts = append(plan.Conflicts, p)
+				}
+				if l.MTime >= r.MTime {
+					push()
+				} else {
+					pull()
+				}
+			case inLocal:
+				push()
+			case inRemote:
+				pull()
+			}
+		}
+	}
+
+	for _, list := range [][]string{plan.Push, plan.Pull, plan.Conflicts} {
+		sort.Strings(list)
+	}
+	plan.DeleteRemote = topLevel(plan.DeleteRemote)
+	plan.DeleteLocal = topLevel(plan.DeleteLocal)
+	return plan
+}
+
+// topLevel sorts paths and drops those inside another path of the list,
+// which are deleted along with it
+func topLevel(paths []string) []string {
+	sort.Strings(paths)
+	var result []string
+	for _, p := range paths {
+		if n := len(result); n > 0 && strings.HasPrefix(p, result[n-1]+"/") {
+			continue
+		}
+		result = append(result, p)
+	}
+	return result
+}